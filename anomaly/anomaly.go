@@ -0,0 +1,167 @@
+// Package anomaly flags partner readings that are more likely API glitches
+// than real price changes, so they can be reviewed before anyone acts on
+// them.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/history"
+)
+
+const (
+	windowEnvKey     = "ANOMALY_WINDOW"
+	minSamplesEnvKey = "ANOMALY_MIN_SAMPLES"
+	thresholdEnvKey  = "ANOMALY_THRESHOLD"
+)
+
+const (
+	defaultWindow     = 20
+	defaultMinSamples = 5
+	defaultThreshold  = 3.5
+
+	// maxRatio is how far a price may move from the median when the recent
+	// prices never varied, so MAD can't tell.
+	maxRatio = 10
+)
+
+// Detector uses the modified z-score, based on the median absolute
+// deviation, of a price against the recent prices of its SKU.
+type Detector struct {
+	window     int
+	minSamples int
+	threshold  float64
+}
+
+func NewDetector() *Detector {
+	d := &Detector{
+		window:     defaultWindow,
+		minSamples: defaultMinSamples,
+		threshold:  defaultThreshold,
+	}
+	if v, err := strconv.Atoi(os.Getenv(windowEnvKey)); err == nil && v > 0 {
+		d.window = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(minSamplesEnvKey)); err == nil && v > 0 {
+		d.minSamples = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv(thresholdEnvKey), 64); err == nil && v > 0 {
+		d.threshold = v
+	}
+	return d
+}
+
+// Detect tells why a reading of price and stock is suspicious, given the
+// past prices of the SKU oldest first. It returns "" for normal readings.
+func (d *Detector) Detect(past []int, price, stock int) string {
+	if price <= 0 {
+		return fmt.Sprintf("impossible price: %d", price)
+	}
+	if stock < 0 {
+		return fmt.Sprintf("impossible stock: %d", stock)
+	}
+
+	if len(past) > d.window {
+		past = past[len(past)-d.window:]
+	}
+	if len(past) < d.minSamples {
+		return ""
+	}
+
+	med := median(past)
+	deviations := make([]float64, len(past))
+	for i, p := range past {
+		deviations[i] = math.Abs(float64(p) - med)
+	}
+	mad := medianFloat(deviations)
+
+	if mad == 0 {
+		ratio := float64(price) / med
+		if ratio >= maxRatio || ratio <= 1.0/maxRatio {
+			return fmt.Sprintf("price %d is %.1fx the usual %.0f", price, ratio, med)
+		}
+		return ""
+	}
+
+	z := 0.6745 * (float64(price) - med) / mad
+	if math.Abs(z) > d.threshold {
+		return fmt.Sprintf("price %d deviates from the median %.0f (modified z-score %.1f)", price, med, z)
+	}
+	return ""
+}
+
+// Quarantine checks the readings of a run against past observations. The
+// price and stock level findings of suspicious readings are replaced with
+// needs-review findings. It returns the reasons by row.
+//
+// Readings are compared with the past prices of their SKU, or of their slug
+// and variant when the rows have no SKU.
+func (d *Detector) Quarantine(report *checker.Report, past []history.Observation) map[int]string {
+	prices := map[string][]int{}
+	for _, o := range past {
+		if o.Price != nil && !o.Suspicious {
+			k := readingKey(o.SKU, o.Slug, o.Variant)
+			prices[k] = append(prices[k], *o.Price)
+		}
+	}
+
+	suspicious := map[int]string{}
+	for _, o := range report.Observations {
+		k := readingKey(o.SKU, o.Slug, o.Variant.Name)
+		if reason := d.Detect(prices[k], o.Variant.Price, o.Variant.Stock); reason != "" {
+			suspicious[o.Row] = reason
+			report.Findings = append(report.Findings, checker.Finding{
+				Kind:     checker.KindNeedsReview,
+				Partner:  report.Partner,
+				Row:      o.Row,
+				SKU:      o.SKU,
+				Slug:     o.Slug,
+				Variant:  o.Variant.Name,
+				NewValue: o.Variant.Price,
+				Message:  reason,
+			})
+		}
+	}
+
+	findings := report.Findings[:0]
+	for _, f := range report.Findings {
+		if _, ok := suspicious[f.Row]; ok && (f.Kind == checker.KindPriceChanged || f.Kind == checker.KindStockLevelChanged) {
+			continue
+		}
+		findings = append(findings, f)
+	}
+	report.Findings = findings
+
+	return suspicious
+}
+
+func readingKey(sku, slug, variant string) string {
+	if sku != "" {
+		return "sku|" + sku
+	}
+	return "slug|" + slug + "|" + variant
+}
+
+func median(values []int) float64 {
+	f := make([]float64, len(values))
+	for i, v := range values {
+		f[i] = float64(v)
+	}
+	return medianFloat(f)
+}
+
+func medianFloat(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
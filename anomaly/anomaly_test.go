@@ -0,0 +1,101 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/history"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestDetector_Detect(t *testing.T) {
+	d := &Detector{window: 20, minSamples: 5, threshold: 3.5}
+
+	tests := []struct {
+		name           string
+		past           []int
+		price          int
+		stock          int
+		wantSuspicious bool
+	}{
+		{name: "zero price", past: nil, price: 0, stock: 10, wantSuspicious: true},
+		{name: "negative stock", past: nil, price: 1000, stock: -1, wantSuspicious: true},
+		{name: "not enough history", past: []int{1000, 1000}, price: 100000, stock: 10, wantSuspicious: false},
+		{name: "normal change", past: []int{1000, 1100, 1000, 1050, 1000}, price: 1100, stock: 10, wantSuspicious: false},
+		{name: "100x price", past: []int{1000, 1100, 1000, 1050, 1000}, price: 100000, stock: 10, wantSuspicious: true},
+		{name: "constant history, normal change", past: []int{1000, 1000, 1000, 1000, 1000}, price: 1200, stock: 10, wantSuspicious: false},
+		{name: "constant history, 100x price", past: []int{1000, 1000, 1000, 1000, 1000}, price: 100000, stock: 10, wantSuspicious: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Detect(tt.past, tt.price, tt.stock); (got != "") != tt.wantSuspicious {
+				t.Errorf("Detector.Detect() = %q, wantSuspicious %v", got, tt.wantSuspicious)
+			}
+		})
+	}
+}
+
+func TestDetector_Quarantine(t *testing.T) {
+	d := &Detector{window: 20, minSamples: 3, threshold: 3.5}
+
+	var past []history.Observation
+	for i := 0; i < 3; i++ {
+		past = append(past, history.Observation{Time: time.Now(), SKU: "SKU-1", Price: history.Int(1000)})
+	}
+
+	report := &checker.Report{
+		Findings: []checker.Finding{
+			{Kind: checker.KindPriceChanged, Row: 1, SKU: "SKU-1", OldValue: 1000, NewValue: 100000},
+			{Kind: checker.KindPriceChanged, Row: 2, SKU: "SKU-2", OldValue: 1000, NewValue: 1200},
+		},
+		Observations: []checker.Observation{
+			{Row: 1, SKU: "SKU-1", Variant: product.Variant{Price: 100000, Stock: 10}},
+			{Row: 2, SKU: "SKU-2", Variant: product.Variant{Price: 1200, Stock: 10}},
+		},
+	}
+
+	suspicious := d.Quarantine(report, past)
+	if _, ok := suspicious[1]; !ok || len(suspicious) != 1 {
+		t.Errorf("Detector.Quarantine() = %v, want only row 1", suspicious)
+	}
+
+	want := map[checker.Kind]int{checker.KindPriceChanged: 1, checker.KindNeedsReview: 1}
+	got := report.CountByKind()
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("findings of kind %v = %v, want %v", kind, got[kind], n)
+		}
+	}
+}
+
+func TestDetector_Quarantine_withoutSKU(t *testing.T) {
+	d := &Detector{window: 20, minSamples: 3, threshold: 3.5}
+
+	var past []history.Observation
+	for i := 0; i < 3; i++ {
+		past = append(past,
+			history.Observation{Time: time.Now(), Slug: "red-shirt", Variant: "L", Price: history.Int(1000)},
+			history.Observation{Time: time.Now(), Slug: "old-hat", Price: history.Int(100000)},
+		)
+	}
+
+	report := &checker.Report{
+		Findings: []checker.Finding{
+			{Kind: checker.KindPriceChanged, Row: 1, Slug: "red-shirt", Variant: "L", OldValue: 1000, NewValue: 100000},
+			{Kind: checker.KindPriceChanged, Row: 2, Slug: "old-hat", OldValue: 90000, NewValue: 100000},
+		},
+		Observations: []checker.Observation{
+			{Row: 1, Slug: "red-shirt", Variant: product.Variant{Name: "L", Price: 100000, Stock: 10}},
+			{Row: 2, Slug: "old-hat", Variant: product.Variant{Price: 100000, Stock: 10}},
+		},
+	}
+
+	suspicious := d.Quarantine(report, past)
+	if _, ok := suspicious[1]; !ok || len(suspicious) != 1 {
+		t.Errorf("Detector.Quarantine() = %v, want only row 1", suspicious)
+	}
+	if got := report.CountByKind()[checker.KindPriceChanged]; got != 1 {
+		t.Errorf("price changed findings = %v, want 1", got)
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 960
+	chartHeight = 280
+
+	chartLeft   = 90
+	chartRight  = 20
+	chartTop    = 36
+	chartBottom = 64
+)
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b"}
+
+type point struct {
+	t time.Time
+	v float64
+}
+
+type series struct {
+	name   string
+	points []point
+}
+
+// chart is a line chart of series over time, drawn as SVG.
+type chart struct {
+	title  string
+	series []series
+
+	// step draws the lines as steps, for values that hold until the next
+	// reading.
+	step bool
+
+	// yTicks are the values marked on the y axis, and yLabel their labels.
+	// When yTicks is nil they are spread over the range of the values.
+	yTicks []float64
+	yLabel func(float64) string
+}
+
+func (c chart) svg() template.HTML {
+	minT, maxT, minV, maxV, ok := c.bounds()
+	if !ok {
+		return ""
+	}
+	if !maxT.After(minT) {
+		minT, maxT = minT.Add(-time.Hour), maxT.Add(time.Hour)
+	}
+
+	ticks := c.yTicks
+	if ticks == nil {
+		ticks = spread(minV, maxV)
+	}
+	minV, maxV = math.Min(minV, ticks[0]), math.Max(maxV, ticks[len(ticks)-1])
+	if maxV == minV {
+		minV, maxV = minV-1, maxV+1
+	}
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	x := func(t time.Time) float64 {
+		return chartLeft + plotW*float64(t.Sub(minT))/float64(maxT.Sub(minT))
+	}
+	y := func(v float64) float64 {
+		return chartTop + plotH*(1-(v-minV)/(maxV-minV))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s" font-family="sans-serif" font-size="12">`,
+		chartWidth, chartHeight, chartWidth, chartHeight, html.EscapeString(c.title))
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`, chartLeft, html.EscapeString(c.title))
+
+	for _, v := range ticks {
+		fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="#e5e5e5"/>`, chartLeft, chartWidth-chartRight, y(v), y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle" fill="#555">%s</text>`, chartLeft-8, y(v), html.EscapeString(c.label(v)))
+	}
+
+	layout := "Jan 2"
+	if maxT.Sub(minT) < 48*time.Hour {
+		layout = "Jan 2 15:04"
+	}
+	for i := 0; i <= 4; i++ {
+		t := minT.Add(maxT.Sub(minT) * time.Duration(i) / 4)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#555">%s</text>`, x(t), chartHeight-chartBottom+18, t.Format(layout))
+	}
+	fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%d" y2="%d" stroke="#999"/>`, chartLeft, chartWidth-chartRight, chartHeight-chartBottom, chartHeight-chartBottom)
+
+	for i, s := range c.series {
+		color := chartColors[i%len(chartColors)]
+
+		var pts []string
+		for j, p := range s.points {
+			if c.step && j > 0 {
+				pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(p.t), y(s.points[j-1].v)))
+			}
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(p.t), y(p.v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(pts, " "))
+
+		for _, p := range s.points {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s: %s, %s</title></circle>`,
+				x(p.t), y(p.v), color, html.EscapeString(s.name), p.t.Format("2006-01-02 15:04"), html.EscapeString(c.label(p.v)))
+		}
+
+		lx := chartLeft + 180*i
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`, lx, chartHeight-22, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, lx+18, chartHeight-12, html.EscapeString(s.name))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func (c chart) bounds() (minT, maxT time.Time, minV, maxV float64, ok bool) {
+	for _, s := range c.series {
+		for _, p := range s.points {
+			if !ok {
+				minT, maxT, minV, maxV, ok = p.t, p.t, p.v, p.v, true
+				continue
+			}
+			if p.t.Before(minT) {
+				minT = p.t
+			}
+			if p.t.After(maxT) {
+				maxT = p.t
+			}
+			minV, maxV = math.Min(minV, p.v), math.Max(maxV, p.v)
+		}
+	}
+	return minT, maxT, minV, maxV, ok
+}
+
+func (c chart) label(v float64) string {
+	if c.yLabel != nil {
+		return c.yLabel(v)
+	}
+	return formatNumber(v)
+}
+
+// spread returns about five round values covering min to max.
+func spread(min, max float64) []float64 {
+	if max == min {
+		return []float64{min}
+	}
+
+	step := math.Pow(10, math.Floor(math.Log10((max-min)/4)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if (max-min)/(step*m) <= 5 {
+			step *= m
+			break
+		}
+	}
+
+	var res []float64
+	for v := math.Floor(min/step) * step; v < max+step; v += step {
+		res = append(res, v)
+	}
+	return res
+}
+
+// formatNumber formats whole numbers with thousands separators, like prices
+// in the CSV.
+func formatNumber(v float64) string {
+	s := fmt.Sprintf("%.0f", math.Abs(v))
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if v < 0 {
+		s = "-" + s
+	}
+	return s
+}
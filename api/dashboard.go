@@ -0,0 +1,310 @@
+package api
+
+import (
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/history"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// tokenCookie keeps the API token of dashboard users. It is only sent to the
+// dashboard pages.
+const tokenCookie = "api_token"
+
+// latestObservations is how many observations a SKU page lists.
+const latestObservations = 50
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+	"optional": func(v *int) string {
+		if v == nil {
+			return "-"
+		}
+		return formatNumber(float64(*v))
+	},
+	"stockLevel": func(v *int) string {
+		if v == nil {
+			return "-"
+		}
+		return stockLevelName(float64(*v))
+	},
+	"total": func(counts map[checker.Kind]int) int {
+		n := 0
+		for _, c := range counts {
+			n += c
+		}
+		return n
+	},
+}
+
+var pages = map[string]*template.Template{}
+
+func init() {
+	for _, name := range []string{"runs", "run", "sku", "login"} {
+		pages[name] = template.Must(template.New("layout.html.tmpl").Funcs(templateFuncs).
+			ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl"))
+	}
+}
+
+// page holds what the layout of every page needs.
+type page struct {
+	Title string
+
+	// Refresh reloads the page every few seconds, while runs are going.
+	Refresh bool
+}
+
+type runsPage struct {
+	page
+	Runs []Run
+}
+
+type runPage struct {
+	page
+	Run      Run
+	Report   *checker.Report
+	Findings []checker.Finding
+	Partners []string
+	Kinds    []string
+	Filter   findingFilter
+}
+
+type skuPage struct {
+	page
+	SKU          string
+	NoHistory    bool
+	Observations []history.Observation
+	Latest       []history.Observation
+	Suspicious   int
+	PriceChart   template.HTML
+	StockChart   template.HTML
+}
+
+type loginPage struct {
+	page
+	Failed bool
+}
+
+// findingFilter selects the findings of a run page. Empty fields match
+// everything; SKU matches part of the SKU, in any case.
+type findingFilter struct {
+	Partner string
+	Kind    string
+	SKU     string
+}
+
+func (f findingFilter) match(finding checker.Finding) bool {
+	return (f.Partner == "" || f.Partner == finding.Partner) &&
+		(f.Kind == "" || f.Kind == string(finding.Kind)) &&
+		(f.SKU == "" || strings.Contains(strings.ToLower(finding.SKU), strings.ToLower(f.SKU)))
+}
+
+func render(w http.ResponseWriter, code int, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	if err := pages[name].Execute(w, data); err != nil {
+		log.Println("[ERROR] [rendering "+name+" page]", err)
+	}
+}
+
+// handleRoot sends browsers to the dashboard.
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	http.Redirect(w, r, "/ui/", http.StatusFound)
+}
+
+// handleLogin takes the API token in a form and keeps it in a cookie, so
+// browsers can see the dashboard.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	data := loginPage{page: page{Title: "Sign in"}}
+	if r.Method != http.MethodPost {
+		render(w, http.StatusOK, "login", data)
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if !s.validToken(token) {
+		data.Failed = true
+		render(w, http.StatusUnauthorized, "login", data)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Value:    token,
+		Path:     "/ui/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/ui/", http.StatusSeeOther)
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/ui/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := runsPage{page: page{Title: "Runs"}}
+	s.mu.Lock()
+	for i := len(s.order) - 1; i >= 0; i-- {
+		run := s.view(s.runs[s.order[i]])
+		data.Runs = append(data.Runs, run)
+		data.Refresh = data.Refresh || run.Status == StatusQueued || run.Status == StatusRunning
+	}
+	s.mu.Unlock()
+
+	render(w, http.StatusOK, "runs", data)
+}
+
+func (s *Server) handleDashboardRun(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/ui/runs/")
+
+	s.mu.Lock()
+	run, ok := s.runs[id]
+	var data runPage
+	if ok {
+		data.Run = s.view(run)
+		data.Report = run.report
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	data.Title = "Run " + id
+	data.Refresh = data.Report == nil && (data.Run.Status == StatusQueued || data.Run.Status == StatusRunning)
+	data.Filter = findingFilter{Partner: q.Get("partner"), Kind: q.Get("kind"), SKU: strings.TrimSpace(q.Get("sku"))}
+
+	if data.Report != nil {
+		partners, kinds := map[string]bool{}, map[string]bool{}
+		for _, f := range data.Report.Findings {
+			if f.Partner != "" {
+				partners[f.Partner] = true
+			}
+			kinds[string(f.Kind)] = true
+			if data.Filter.match(f) {
+				data.Findings = append(data.Findings, f)
+			}
+		}
+		data.Partners, data.Kinds = sortedSet(partners), sortedSet(kinds)
+	}
+
+	render(w, http.StatusOK, "run", data)
+}
+
+func (s *Server) handleDashboardSKU(w http.ResponseWriter, r *http.Request) {
+	sku, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/ui/skus/"))
+	if err != nil || sku == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := skuPage{page: page{Title: "SKU " + sku}, SKU: sku, NoHistory: s.history == nil}
+	if s.history != nil {
+		observations, err := s.history.Query(history.Filter{SKU: sku})
+		if err != nil {
+			log.Println("[ERROR] [querying history]", err)
+			http.Error(w, "reading the history failed", http.StatusInternalServerError)
+			return
+		}
+		data.Observations = observations
+		data.PriceChart, data.StockChart, data.Suspicious = skuCharts(observations)
+
+		for i := len(observations) - 1; i >= 0 && len(data.Latest) < latestObservations; i-- {
+			data.Latest = append(data.Latest, observations[i])
+		}
+	}
+
+	render(w, http.StatusOK, "sku", data)
+}
+
+// skuCharts draws the price and stock level of observations, a line per
+// partner and variant. Suspicious readings are left out and counted.
+func skuCharts(observations []history.Observation) (price, stock template.HTML, suspicious int) {
+	partners := map[string]bool{}
+	for _, o := range observations {
+		partners[o.Partner] = true
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	prices, levels := map[string][]point{}, map[string][]point{}
+	for _, o := range observations {
+		if o.Suspicious {
+			suspicious++
+			continue
+		}
+
+		name := o.Variant
+		if len(partners) > 1 {
+			name = o.Partner + " / " + o.Variant
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		if o.Price != nil {
+			prices[name] = append(prices[name], point{o.Time, float64(*o.Price)})
+		}
+		if o.StockLevel != nil {
+			levels[name] = append(levels[name], point{o.Time, float64(*o.StockLevel)})
+		}
+	}
+
+	priceChart := chart{title: "Price"}
+	stockChart := chart{
+		title:  "Stock level",
+		step:   true,
+		yTicks: []float64{product.OutOfStock, product.LowStock, product.HighStock},
+		yLabel: stockLevelName,
+	}
+	for _, name := range names {
+		if len(prices[name]) > 0 {
+			priceChart.series = append(priceChart.series, series{name: name, points: prices[name]})
+		}
+		if len(levels[name]) > 0 {
+			stockChart.series = append(stockChart.series, series{name: name, points: levels[name]})
+		}
+	}
+	return priceChart.svg(), stockChart.svg(), suspicious
+}
+
+func stockLevelName(v float64) string {
+	switch int(v) {
+	case product.OutOfStock:
+		return "out of stock"
+	case product.LowStock:
+		return "low"
+	case product.HighStock:
+		return "high"
+	}
+	return strconv.Itoa(int(v))
+}
+
+func sortedSet(m map[string]bool) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
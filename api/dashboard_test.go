@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/history"
+	"github.com/andrysds/dropship-checker/partnertest"
+)
+
+// get fetches the dashboard page at url with the token cookie and returns
+// its status and body.
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.AddCookie(&http.Cookie{Name: tokenCookie, Value: sampleToken})
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestServer_dashboardLogin(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := noRedirect.Get(ts.URL + "/ui/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusFound || loc != "/ui/login" {
+		t.Errorf("GET /ui/ without token = %v %v, want a redirect to /ui/login", resp.StatusCode, loc)
+	}
+
+	resp, err = noRedirect.PostForm(ts.URL+"/ui/login", url.Values{"token": {"sample"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /ui/login with a wrong token status = %v, want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, err = noRedirect.PostForm(ts.URL+"/ui/login", url.Values{"token": {sampleToken}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cookies := resp.Cookies()
+	if resp.StatusCode != http.StatusSeeOther || len(cookies) != 1 || cookies[0].Value != sampleToken || cookies[0].Path != "/ui/" {
+		t.Errorf("POST /ui/login = %v %v, want a redirect with the token cookie", resp.StatusCode, cookies)
+	}
+
+	t.Run("cookie is only for the dashboard", func(t *testing.T) {
+		if code, _ := get(t, noRedirect, ts.URL+"/runs"); code != http.StatusUnauthorized {
+			t.Errorf("GET /runs with the cookie status = %v, want %v", code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestServer_dashboardRun(t *testing.T) {
+	_, ts := newTestServer(t, func(ctx context.Context, c *checker.Checker) (*checker.Report, error) {
+		return &checker.Report{
+			Partner: "acme",
+			Checked: 2,
+			Findings: []checker.Finding{
+				{Kind: checker.KindPriceChanged, Partner: "acme", Row: 1, SKU: "SKU-1", OldValue: 10000, NewValue: 11000},
+				{Kind: checker.KindStockLevelChanged, Partner: "acme", Row: 2, SKU: "SKU-2", OldValue: 2, NewValue: 0},
+				{Kind: checker.KindPriceChanged, Partner: "other", Row: 3, SKU: "OTHER-3", OldValue: 5000, NewValue: 4000},
+			},
+		}, nil
+	})
+
+	var run Run
+	do(t, http.MethodPost, ts.URL+"/runs", "", nil, &run)
+	waitRun(t, ts.URL, run.ID)
+
+	if code, body := get(t, http.DefaultClient, ts.URL+"/ui/"); code != http.StatusOK || !strings.Contains(body, run.ID) {
+		t.Errorf("GET /ui/ = %v, want the run listed", code)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "all", want: []string{"SKU-1", "SKU-2", "OTHER-3"}},
+		{name: "partner", query: "?partner=acme", want: []string{"SKU-1", "SKU-2"}},
+		{name: "kind", query: "?kind=price_changed", want: []string{"SKU-1", "OTHER-3"}},
+		{name: "sku", query: "?sku=sku-", want: []string{"SKU-1", "SKU-2"}},
+		{name: "all filters", query: "?partner=acme&kind=price_changed&sku=2", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := get(t, http.DefaultClient, ts.URL+"/ui/runs/"+run.ID+tt.query)
+			if code != http.StatusOK {
+				t.Fatalf("GET /ui/runs/{id} status = %v, want %v", code, http.StatusOK)
+			}
+
+			var got []string
+			for _, sku := range []string{"SKU-1", "SKU-2", "OTHER-3"} {
+				if strings.Contains(body, `href="/ui/skus/`+sku+`"`) {
+					got = append(got, sku)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GET /ui/runs/{id}%s findings = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_dashboardSKU(t *testing.T) {
+	setEnv(t)
+
+	store := history.NewStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	store.Append(
+		history.Observation{Time: start, SKU: "SKU-1", Variant: "red", Price: history.Int(10000), StockLevel: history.Int(2)},
+		history.Observation{Time: start.Add(24 * time.Hour), SKU: "SKU-1", Variant: "red", Price: history.Int(11000), StockLevel: history.Int(1)},
+		history.Observation{Time: start.Add(48 * time.Hour), SKU: "SKU-1", Variant: "red", Price: history.Int(990000), StockLevel: history.Int(1), Suspicious: true},
+		history.Observation{Time: start, SKU: "SKU-2", Variant: "blue", Price: history.Int(5000)},
+	)
+
+	records := func() ([]csv.Record, error) { return nil, nil }
+	s := NewServer(sampleToken, partnertest.NewPartner(partnertest.Products), records, nil, store)
+	ts := httptest.NewServer(s)
+	defer func() {
+		ts.Close()
+		s.Close()
+		s.Wait()
+	}()
+
+	code, body := get(t, http.DefaultClient, ts.URL+"/ui/skus/SKU-1")
+	if code != http.StatusOK {
+		t.Fatalf("GET /ui/skus/SKU-1 status = %v, want %v", code, http.StatusOK)
+	}
+	if n := strings.Count(body, "<svg"); n != 2 {
+		t.Errorf("GET /ui/skus/SKU-1 charts = %v, want 2", n)
+	}
+	for _, want := range []string{"11,000", "low", "1 suspicious readings"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /ui/skus/SKU-1 doesn't contain %q", want)
+		}
+	}
+	if strings.Contains(body, "5,000") {
+		t.Errorf("GET /ui/skus/SKU-1 contains the price of SKU-2")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		min, max float64
+		want     []float64
+	}{
+		{min: 10000, max: 12000, want: []float64{10000, 10500, 11000, 11500, 12000}},
+		{min: 9500, max: 10200, want: []float64{9400, 9600, 9800, 10000, 10200}},
+		{min: 5, max: 5, want: []float64{5}},
+	}
+	for _, tt := range tests {
+		if got := spread(tt.min, tt.max); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("spread(%v, %v) = %v, want %v", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+)
+
+// Status tells where a run is.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Run is a check run started over the API.
+type Run struct {
+	ID         string               `json:"id"`
+	Status     Status               `json:"status"`
+	Source     string               `json:"source"`
+	CreatedAt  time.Time            `json:"created_at"`
+	StartedAt  *time.Time           `json:"started_at,omitempty"`
+	FinishedAt *time.Time           `json:"finished_at,omitempty"`
+	Progress   Progress             `json:"progress"`
+	Findings   map[checker.Kind]int `json:"findings,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// Progress is how many rows a run got through out of the rows it looks at.
+type Progress struct {
+	Checked int `json:"checked"`
+	Total   int `json:"total"`
+}
+
+// Sources of the CSV of a run.
+const (
+	SourceUpload     = "upload"
+	SourceConfigured = "configured"
+)
+
+// run is a Run with what the server needs to carry it out. Its fields are
+// guarded by the mutex of the server.
+type run struct {
+	Run
+	checker *checker.Checker
+	report  *checker.Report
+}
+
+func newRun(records []csv.Record, p checker.Partner, source string) *run {
+	return &run{
+		Run: Run{
+			ID:        newID(),
+			Status:    StatusQueued,
+			Source:    source,
+			CreatedAt: time.Now(),
+		},
+		checker: checker.NewChecker(records, p),
+	}
+}
+
+// newID returns a random run ID.
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// view returns the Run of r as it is now.
+func (s *Server) view(r *run) Run {
+	v := r.Run
+	if r.Status == StatusQueued || r.Status == StatusRunning {
+		v.Progress.Checked, v.Progress.Total = r.checker.Progress()
+	}
+	return v
+}
+
+// work carries out the queued runs one after the other, so they share the
+// partner without racing each other, until the queue is closed.
+func (s *Server) work() {
+	defer close(s.done)
+	for r := range s.queue {
+		s.mu.Lock()
+		closing := s.closing
+		if closing {
+			r.Status = StatusCanceled
+		} else {
+			now := time.Now()
+			r.Status = StatusRunning
+			r.StartedAt = &now
+		}
+		s.mu.Unlock()
+		if closing {
+			continue
+		}
+
+		log.Printf("[INFO] api run started; id: %s; source: %s\n", r.ID, r.Source)
+		report, err := s.check(context.Background(), r.checker)
+
+		s.mu.Lock()
+		now := time.Now()
+		r.FinishedAt = &now
+		r.report = report
+		r.Status = StatusDone
+		if err != nil {
+			r.Status = StatusFailed
+			r.Error = err.Error()
+		}
+		r.Progress.Checked, r.Progress.Total = r.checker.Progress()
+		if report != nil {
+			r.Findings = report.CountByKind()
+		}
+		s.mu.Unlock()
+
+		log.Printf("[INFO] api run finished; id: %s; status: %s\n", r.ID, r.Status)
+	}
+}
+
+// add keeps r, forgetting the oldest finished runs past maxRuns. The caller
+// holds s.mu.
+func (s *Server) add(r *run) {
+	s.runs[r.ID] = r
+	s.order = append(s.order, r.ID)
+
+	for i := 0; len(s.order) > maxRuns && i < len(s.order); {
+		old := s.runs[s.order[i]]
+		if old.Status == StatusQueued || old.Status == StatusRunning {
+			i++
+			continue
+		}
+		delete(s.runs, old.ID)
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+}
@@ -0,0 +1,293 @@
+// Package api serves check runs over HTTP, so tools like an admin panel can
+// start checks and read their findings, next to a dashboard of the runs for
+// browsers. Every endpoint takes the API token as a bearer token; the
+// dashboard also takes it from the cookie its sign in page sets.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/history"
+	"github.com/andrysds/dropship-checker/metrics"
+)
+
+const (
+	// maxUploadSize limits uploaded CSV files.
+	maxUploadSize = 10 << 20
+
+	// maxRuns is how many runs the server remembers.
+	maxRuns = 100
+
+	// maxQueued is how many runs can wait for the one in flight.
+	maxQueued = 10
+
+	readyTimeout = 10 * time.Second
+)
+
+// CheckFunc carries out a run with c and returns its report, like the check
+// command does.
+type CheckFunc func(ctx context.Context, c *checker.Checker) (*checker.Report, error)
+
+// RecordsFunc reads the configured CSV.
+type RecordsFunc func() ([]csv.Record, error)
+
+// Server serves the check runs API. Runs are carried out one at a time, in
+// the order they were started.
+type Server struct {
+	token   string
+	partner checker.ContextPartner
+	records RecordsFunc
+	check   CheckFunc
+	history *history.Store
+	mux     *http.ServeMux
+
+	queue chan *run
+	done  chan struct{}
+
+	mu      sync.Mutex
+	runs    map[string]*run
+	order   []string
+	closing bool
+}
+
+// NewServer returns a server checking against p, which also tells whether
+// the server is ready. Requests must carry token. The dashboard charts the
+// SKUs of store, which may be nil.
+func NewServer(token string, p checker.ContextPartner, records RecordsFunc, check CheckFunc, store *history.Store) *Server {
+	s := &Server{
+		token:   token,
+		partner: p,
+		records: records,
+		check:   check,
+		history: store,
+		mux:     http.NewServeMux(),
+		queue:   make(chan *run, maxQueued),
+		done:    make(chan struct{}),
+		runs:    map[string]*run{},
+	}
+
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+	s.mux.HandleFunc("/runs", s.handleRuns)
+	s.mux.HandleFunc("/runs/", s.handleRun)
+	s.mux.Handle("/metrics", metrics.Handler())
+
+	s.mux.HandleFunc("/", s.handleRoot)
+	s.mux.HandleFunc("/ui/", s.handleDashboard)
+	s.mux.HandleFunc("/ui/login", s.handleLogin)
+	s.mux.HandleFunc("/ui/runs/", s.handleDashboardRun)
+	s.mux.HandleFunc("/ui/skus/", s.handleDashboardSKU)
+
+	go s.work()
+	return s
+}
+
+// ServeHTTP serves the API to requests with the token. Browsers without it
+// are sent to the sign in page of the dashboard.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dashboard := strings.HasPrefix(r.URL.Path, "/ui/") || r.URL.Path == "/"
+	if !s.authorized(r, dashboard) && r.URL.Path != "/ui/login" {
+		if dashboard && r.Method == http.MethodGet {
+			http.Redirect(w, r, "/ui/login", http.StatusFound)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dropship-checker"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized tells whether r carries the token, in the cookie too for the
+// dashboard.
+func (s *Server) authorized(r *http.Request, dashboard bool) bool {
+	if s.validToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")) {
+		return true
+	}
+	if c, err := r.Cookie(tokenCookie); err == nil && dashboard {
+		return s.validToken(c.Value)
+	}
+	return false
+}
+
+func (s *Server) validToken(token string) bool {
+	return s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// Close stops taking runs. Queued runs are canceled; the one in flight
+// finishes, which Wait waits for.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closing {
+		s.closing = true
+		close(s.queue)
+	}
+}
+
+// Wait waits for the runs to end after Close.
+func (s *Server) Wait() {
+	<-s.done
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady tells whether the server takes runs and can log in to the
+// partner.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	closing := s.closing
+	s.mu.Unlock()
+	if closing {
+		writeError(w, http.StatusServiceUnavailable, "shutting down")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := s.partner.LoginContext(ctx); err != nil {
+		log.Println("[ERROR] [readyz]", err)
+		writeError(w, http.StatusServiceUnavailable, "partner login failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleRuns lists the runs on GET and starts one on POST.
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		res := make([]Run, 0, len(s.order))
+		for i := len(s.order) - 1; i >= 0; i-- {
+			res = append(res, s.view(s.runs[s.order[i]]))
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string][]Run{"runs": res})
+
+	case http.MethodPost:
+		s.startRun(w, r)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// startRun queues a run of the uploaded CSV, or of the configured one when
+// the request has no body.
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	records, source, err := s.readRecords(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	run := newRun(records, s.partner, source)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		writeError(w, http.StatusServiceUnavailable, "shutting down")
+		return
+	}
+	select {
+	case s.queue <- run:
+	default:
+		writeError(w, http.StatusServiceUnavailable, "too many queued runs")
+		return
+	}
+	s.add(run)
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, s.view(run))
+}
+
+func (s *Server) readRecords(w http.ResponseWriter, r *http.Request) ([]csv.Record, string, error) {
+	if r.ContentLength == 0 {
+		records, err := s.records()
+		if err != nil {
+			log.Println("[ERROR] [reading configured csv]", err)
+			return nil, "", errors.New("reading the configured CSV: " + err.Error())
+		}
+		return records, SourceConfigured, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		records, err := csv.NewCSV(r.Body)
+		return records, SourceUpload, err
+
+	case "multipart/form-data":
+		f, _, err := r.FormFile("csv")
+		if err != nil {
+			return nil, "", errors.New("reading the csv form file: " + err.Error())
+		}
+		defer f.Close()
+		records, err := csv.NewCSV(f)
+		return records, SourceUpload, err
+	}
+	return nil, "", errors.New("upload the CSV as text/csv or as the csv file of a multipart/form-data form")
+}
+
+// handleRun serves /runs/{id} and /runs/{id}/findings.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+	if len(path) > 2 || (len(path) == 2 && path[1] != "findings") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[path[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+
+	if len(path) == 1 {
+		writeJSON(w, http.StatusOK, s.view(run))
+		return
+	}
+
+	if run.report == nil {
+		writeError(w, http.StatusConflict, "run is "+string(run.Status)+"; findings are ready when it ends")
+		return
+	}
+	writeJSON(w, http.StatusOK, run.report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("[ERROR] [writing response]", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,37 @@
+// Package backoff holds the retry delays shared by the partner client and
+// the notifiers.
+package backoff
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Delay returns the delay before the retry after attempt: base doubled for
+// every attempt so far, capped at max, with the upper half jittered.
+func Delay(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Sleep waits for d or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/andrysds/dropship-checker/anomaly"
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/history"
+	"github.com/andrysds/dropship-checker/metrics"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/rules"
+	"github.com/andrysds/dropship-checker/snapshot"
+)
+
+func runCheck(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	reportPath := fs.String("report", "", "write the JSON report of the run to this path")
+	snapshotPath := fs.String("snapshot", "", "check against this snapshot file instead of the partner, without notifying")
+	baselinePath := fs.String("baseline", "", "report product changes since this snapshot file, then update it")
+	fs.Parse(args)
+
+	r := loadRecords(ctx)
+
+	var p checker.Partner
+	var live *partner.Partner
+	if *snapshotPath != "" {
+		s, err := snapshot.Load(*snapshotPath)
+		if err != nil {
+			fatal(ctx, "loading snapshot", err)
+		}
+		p = snapshot.NewPartner(s)
+	} else {
+		live = newPartner(ctx)
+		p = live
+	}
+
+	opts := checkOptions{
+		reportPath:   *reportPath,
+		baselinePath: *baselinePath,
+		history:      optionalHistoryStore(),
+		snapshot:     *snapshotPath != "",
+	}
+	_, err := check(ctx, checker.NewChecker(r, p), opts)
+	if live != nil {
+		closePartner(live)
+	}
+	if err != nil {
+		log.Fatalln("[ERROR] [Check]", err)
+	}
+}
+
+type checkOptions struct {
+	reportPath   string
+	baselinePath string
+	// history is nil when HISTORY_PATH is not set.
+	history *history.Store
+	// snapshot runs check stale readings, so they stay out of the history
+	// and don't alert or touch the alert state of live runs.
+	snapshot bool
+}
+
+// check runs c and everything that comes after a run: ops alerts,
+// quarantine, history, the baseline, rules, notifications and the report
+// file. Their failures are logged; the error is the run's. Snapshot runs
+// skip the history, ops alerts and notifications.
+func check(ctx context.Context, c *checker.Checker, opts checkOptions) (*checker.Report, error) {
+	report, err := c.CheckContext(ctx)
+	metrics.ObserveRun(report, err)
+
+	if !opts.snapshot {
+		if err := opsAlerts().CheckRun(ctx, report, err); err != nil {
+			log.Println("[ERROR] [sending ops alerts]", err)
+		}
+	}
+
+	suspicious := quarantine(opts.history, report)
+	if opts.history != nil && !opts.snapshot {
+		if err := recordHistory(opts.history, report, suspicious); err != nil {
+			log.Println("[ERROR] [recording history]", err)
+		}
+	}
+
+	if opts.baselinePath != "" {
+		if err := diffBaseline(opts.baselinePath, report, suspicious); err != nil {
+			log.Println("[ERROR] [diffing baseline]", err)
+		}
+	}
+
+	// Rules see the baseline changes and skip the quarantined readings.
+	if err := applyRules(report); err != nil {
+		log.Println("[ERROR] [applying rules]", err)
+	}
+
+	if !opts.snapshot {
+		if err := notifier().Notify(ctx, report); err != nil {
+			log.Println("[ERROR] [notifying]", err)
+		}
+	}
+
+	log.Printf("checked rows: %d; findings: %d; unchecked rows: %d\n", report.Checked, len(report.Findings), len(report.Unchecked))
+
+	if opts.reportPath != "" {
+		if err := writeReport(opts.reportPath, report); err != nil {
+			log.Println("[ERROR] [writing report]", err)
+		}
+	}
+
+	return report, err
+}
+
+func writeReport(path string, report *checker.Report) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// quarantine replaces the findings of readings that look like partner
+// glitches with needs-review findings and returns the reasons by row.
+// Without a history store only impossible readings are caught.
+func quarantine(store *history.Store, report *checker.Report) map[int]string {
+	var past []history.Observation
+	if store != nil {
+		var err error
+		if past, err = store.Query(history.Filter{Partner: report.Partner}); err != nil {
+			log.Println("[ERROR] [querying history]", err)
+		}
+	}
+
+	suspicious := anomaly.NewDetector().Quarantine(report, past)
+	for row, reason := range suspicious {
+		log.Printf("[WARN] suspicious reading needs review; row: %d; %s\n", row, reason)
+	}
+	return suspicious
+}
+
+// recordHistory adds the readings of the run to the history, marking the
+// suspicious ones.
+func recordHistory(store *history.Store, report *checker.Report, suspicious map[int]string) error {
+	observations := history.FromReport(report)
+	for i, o := range report.Observations {
+		_, observations[i].Suspicious = suspicious[o.Row]
+	}
+	return store.Append(observations...)
+}
+
+// applyRules adds the matches of the rules of RULES_PATH to the report and
+// gives findings their severity and routes.
+func applyRules(report *checker.Report) error {
+	e, err := rules.NewEngine()
+	if err != nil || e == nil {
+		return err
+	}
+
+	n := len(report.Findings)
+	err = e.Apply(report)
+	for _, f := range report.Findings[n:] {
+		log.Printf("[WARN] rule matched; row: %d; rule: %s; sku: %s\n", f.Row, f.Rule, f.SKU)
+	}
+	return err
+}
+
+// diffBaseline adds the product changes since the baseline snapshot to the
+// report and stores the fetched products as the new baseline. Suspicious
+// readings are matched by slug and variant and stay out of both.
+func diffBaseline(path string, report *checker.Report, suspicious map[int]string) error {
+	baseline, err := snapshot.Load(path)
+	if os.IsNotExist(err) {
+		baseline = snapshot.New()
+	} else if err != nil {
+		return err
+	}
+
+	skip := map[string]map[string]bool{}
+	for _, o := range report.Observations {
+		if _, ok := suspicious[o.Row]; ok {
+			if skip[o.Slug] == nil {
+				skip[o.Slug] = map[string]bool{}
+			}
+			skip[o.Slug][o.Variant.Name] = true
+		}
+	}
+	current := baseline.Without(report.Products, skip)
+
+	changes := baseline.Diff(current)
+	for _, change := range changes {
+		log.Printf("[WARN] product change detected; slug: %s; %s\n", change.Slug, change)
+	}
+	report.Findings = append(report.Findings, snapshot.Findings(report.Partner, changes)...)
+
+	baseline.Update(current)
+	baseline.CreatedAt = report.StartedAt
+	return baseline.Save(path)
+}
@@ -1,12 +1,17 @@
 package checker
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/partner"
 	"github.com/andrysds/dropship-checker/product"
 )
 
@@ -16,6 +21,7 @@ const (
 	productSlugKeyEnvKey = "PRODUCT_SLUG_KEY"
 	variantNameKeyEnvKey = "VARIANT_NAME_KEY"
 	skuKeyEnvKey         = "SKU_KEY"
+	PartnerNameEnvKey    = "PARTNER_NAME"
 )
 
 type Partner interface {
@@ -23,6 +29,14 @@ type Partner interface {
 	GetProduct(slug string) (*product.Product, error)
 }
 
+// ContextPartner is a Partner whose calls can be canceled and given
+// deadlines. Checker prefers it over the plain Partner methods.
+type ContextPartner interface {
+	Partner
+	LoginContext(ctx context.Context) error
+	GetProductContext(ctx context.Context, slug string) (*product.Product, error)
+}
+
 type Checker struct {
 	records        []csv.Record
 	partner        Partner
@@ -31,6 +45,10 @@ type Checker struct {
 	productSlugKey string
 	variantKey     string
 	skuKey         string
+	partnerName    string
+
+	// checked is the number of rows the running check got through.
+	checked int64
 }
 
 func NewChecker(records []csv.Record, partner Partner) *Checker {
@@ -42,14 +60,33 @@ func NewChecker(records []csv.Record, partner Partner) *Checker {
 		productSlugKey: os.Getenv(productSlugKeyEnvKey),
 		variantKey:     os.Getenv(variantNameKeyEnvKey),
 		skuKey:         os.Getenv(skuKeyEnvKey),
+		partnerName:    os.Getenv(PartnerNameEnvKey),
 	}
 }
 
 func (c *Checker) Check() error {
-	if err := c.partner.Login(); err != nil {
-		return err
+	_, err := c.CheckContext(context.Background())
+	return err
+}
+
+// CheckContext checks every row against the partner until ctx is done. When
+// the run is stopped early, the partial report is returned together with
+// the reason.
+func (c *Checker) CheckContext(ctx context.Context) (*Report, error) {
+	atomic.StoreInt64(&c.checked, 0)
+	report := &Report{
+		StartedAt: time.Now(),
+		Partner:   c.partnerName,
+		Findings:  []Finding{},
+		Products:  map[string]*product.Product{},
+	}
+	defer func() { report.FinishedAt = time.Now() }()
+
+	if err := c.login(ctx); err != nil {
+		return report, err
 	}
 
+	unavailable := false
 	for i, record := range c.records {
 		data := record.Data
 		slug := data[c.productSlugKey]
@@ -58,16 +95,62 @@ func (c *Checker) Check() error {
 			break
 		}
 
-		product, err := c.partner.GetProduct(slug)
+		if err := ctx.Err(); err != nil {
+			return c.stop(report, i, err)
+		}
+
+		var attempts int
+		product, err := c.getProduct(partner.WithAttemptCounter(ctx, &attempts), slug)
+		if attempts > 1 {
+			log.Printf("[INFO] request retried; row: %d; attempts: %d; slug: %s\n", i+1, attempts, slug)
+		}
+		if errors.Is(err, partner.ErrBudgetExhausted) {
+			report.Unchecked = c.uncheckedRows(i)
+			log.Printf("[WARN] daily call budget exhausted; unchecked rows: %s\n", joinRows(report.Unchecked))
+			break
+		}
+		if err != nil && ctx.Err() != nil {
+			return c.stop(report, i, ctx.Err())
+		}
+
+		report.Checked++
+		atomic.StoreInt64(&c.checked, int64(report.Checked))
+		sku := data[c.skuKey]
+		finding := Finding{Partner: c.partnerName, Row: i + 1, SKU: sku, Slug: slug, Variant: data[c.variantKey]}
+
+		if errors.Is(err, partner.ErrPartnerUnavailable) {
+			if !unavailable {
+				log.Println("[ERROR] partner unavailable; skipping requests until it recovers")
+				unavailable = true
+			}
+			finding.Kind = KindPartnerUnavailable
+			finding.Message = err.Error()
+			report.Findings = append(report.Findings, finding)
+			continue
+		}
+		unavailable = false
+
+		if errors.Is(err, partner.ErrProductNotFound) {
+			log.Printf("[WARN] product discontinued; row: %d; slug: %s; sku: %s\n", i+1, slug, sku)
+			finding.Kind = KindDiscontinued
+			report.Findings = append(report.Findings, finding)
+			continue
+		}
+
 		if err != nil {
 			log.Println("[ERROR] [GetProduct]", err)
+			finding.Kind = errorKind(err)
+			finding.Message = err.Error()
+			report.Findings = append(report.Findings, finding)
+			continue
 		}
 
+		report.Products[slug] = product
+
 		found := false
 		for _, variant := range product.Variants {
 			if variant.Name == record.Data[c.variantKey] {
 				found = true
-				sku := record.Data[c.skuKey]
 				oldPriceStr := data[c.priceKey]
 				oldPriceStr = strings.ReplaceAll(oldPriceStr, "Rp", "")
 				oldPriceStr = strings.ReplaceAll(oldPriceStr, ",", "")
@@ -77,7 +160,12 @@ func (c *Checker) Check() error {
 				}
 
 				if variant.IsPriceChanged(int(oldPrice)) {
-					log.Printf("[WARN] price change detected; row: %d; new price: %d; sku: %s\n", i+1, variant.Price, sku)
+					log.Printf("[WARN] price change detected; row: %d; new price: %d; sku: %s; slug: %s; variant: %s\n", i+1, variant.Price, sku, slug, variant.Name)
+					f := finding
+					f.Kind = KindPriceChanged
+					f.OldValue = int(oldPrice)
+					f.NewValue = variant.Price
+					report.Findings = append(report.Findings, f)
 				}
 
 				oldStockLevel, err := strconv.ParseInt(data[c.stockLevelKey], 10, 32)
@@ -85,8 +173,23 @@ func (c *Checker) Check() error {
 					log.Println("[ERROR] [parsing old stock level]", err)
 				}
 
+				report.Observations = append(report.Observations, Observation{
+					Row:           i + 1,
+					SKU:           sku,
+					Slug:          slug,
+					Variant:       variant,
+					OldPrice:      int(oldPrice),
+					OldStockLevel: int(oldStockLevel),
+					Data:          data,
+				})
+
 				if variant.IsStockLevelChange(int(oldStockLevel)) {
-					log.Printf("[WARN] stock level change detected; row: %d; new stock level: %d; sku: %s\n", i+1, variant.StockLevel(), sku)
+					log.Printf("[WARN] stock level change detected; row: %d; new stock level: %d; sku: %s; slug: %s; variant: %s\n", i+1, variant.StockLevel(), sku, slug, variant.Name)
+					f := finding
+					f.Kind = KindStockLevelChanged
+					f.OldValue = int(oldStockLevel)
+					f.NewValue = variant.StockLevel()
+					report.Findings = append(report.Findings, f)
 				}
 
 				break
@@ -95,8 +198,82 @@ func (c *Checker) Check() error {
 
 		if !found {
 			log.Printf("[ERROR] product: %v,  variant: %v, not found", product.Name, data[c.variantKey])
+			finding.Kind = KindVariantNotFound
+			report.Findings = append(report.Findings, finding)
 		}
 	}
 
-	return nil
+	return report, nil
+}
+
+// errorKind tells which kind of finding a failed GetProduct call is.
+func errorKind(err error) Kind {
+	var decodeErr *partner.DecodeError
+	switch {
+	case errors.Is(err, partner.ErrUnauthorized):
+		return KindUnauthorized
+	case errors.Is(err, partner.ErrRateLimited):
+		return KindRateLimited
+	case errors.As(err, &decodeErr):
+		return KindMalformedResponse
+	}
+	return KindPartnerError
+}
+
+func (c *Checker) login(ctx context.Context) error {
+	if p, ok := c.partner.(ContextPartner); ok {
+		return p.LoginContext(ctx)
+	}
+	return c.partner.Login()
+}
+
+func (c *Checker) getProduct(ctx context.Context, slug string) (*product.Product, error) {
+	if p, ok := c.partner.(ContextPartner); ok {
+		return p.GetProductContext(ctx, slug)
+	}
+	return c.partner.GetProduct(slug)
+}
+
+// stop ends the run at row index i because of err.
+func (c *Checker) stop(report *Report, i int, err error) (*Report, error) {
+	report.Unchecked = c.uncheckedRows(i)
+	log.Printf("[WARN] run stopped: %v; unchecked rows: %s\n", err, joinRows(report.Unchecked))
+	return report, err
+}
+
+// uncheckedRows lists the row numbers from index from up to the first row
+// without a product slug.
+func (c *Checker) uncheckedRows(from int) []int {
+	var rows []int
+	for i := from; i < len(c.records); i++ {
+		if c.records[i].Data[c.productSlugKey] == "" {
+			break
+		}
+		rows = append(rows, i+1)
+	}
+	return rows
+}
+
+// Progress tells how many rows the running, or last, check got through out
+// of the rows it looks at. It is safe to call while the check runs.
+func (c *Checker) Progress() (checked, total int) {
+	return int(atomic.LoadInt64(&c.checked)), len(c.uncheckedRows(0))
+}
+
+// Slugs returns the distinct product slugs of the rows Check looks at, in
+// row order.
+func (c *Checker) Slugs() []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, record := range c.records {
+		slug := record.Data[c.productSlugKey]
+		if slug == "" {
+			break
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			res = append(res, slug)
+		}
+	}
+	return res
 }
@@ -1,12 +1,15 @@
 package checker
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"testing"
 
 	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/partner"
 	"github.com/andrysds/dropship-checker/product"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestNewChecker(t *testing.T) {
@@ -94,3 +97,162 @@ func TestChecker_Check(t *testing.T) {
 		t.Errorf("Checker.Check() error = %v, wantErr %v", err, wantErr)
 	}
 }
+
+func TestChecker_Check_budgetExhausted(t *testing.T) {
+	mockProductSlugKey := "header3"
+
+	mockRecords := []csv.Record{
+		{Data: map[string]string{"header3": "slug-1"}},
+		{Data: map[string]string{"header3": "slug-2"}},
+	}
+
+	mockPartner := &MockPartner{}
+	mockPartner.On("Login").Return(nil)
+	mockPartner.On("GetProduct", "slug-1").Return(nil, partner.ErrBudgetExhausted)
+
+	c := &Checker{
+		records:        mockRecords,
+		partner:        mockPartner,
+		productSlugKey: mockProductSlugKey,
+	}
+
+	report, err := c.CheckContext(context.Background())
+	if err != nil {
+		t.Errorf("Checker.CheckContext() error = %v, wantErr %v", err, false)
+	}
+	mockPartner.AssertNotCalled(t, "GetProduct", "slug-2")
+
+	if want := []int{1, 2}; !reflect.DeepEqual(report.Unchecked, want) {
+		t.Errorf("Report.Unchecked = %v, want %v", report.Unchecked, want)
+	}
+	if report.Checked != 0 {
+		t.Errorf("Report.Checked = %v, want 0", report.Checked)
+	}
+}
+
+func TestChecker_Progress(t *testing.T) {
+	mockRecords := []csv.Record{
+		{Data: map[string]string{"header3": "slug-1"}},
+		{Data: map[string]string{"header3": "slug-2"}},
+		{Data: map[string]string{"header3": ""}},
+	}
+
+	mockPartner := &MockPartner{}
+	mockPartner.On("Login").Return(nil)
+	mockPartner.On("GetProduct", "slug-1").Return(&product.Product{}, nil)
+	mockPartner.On("GetProduct", "slug-2").Return(&product.Product{}, nil)
+
+	c := &Checker{
+		records:        mockRecords,
+		partner:        mockPartner,
+		productSlugKey: "header3",
+	}
+
+	if checked, total := c.Progress(); checked != 0 || total != 2 {
+		t.Errorf("Checker.Progress() before Check = %v, %v, want 0, 2", checked, total)
+	}
+	c.Check()
+	if checked, total := c.Progress(); checked != 2 || total != 2 {
+		t.Errorf("Checker.Progress() = %v, %v, want 2, 2", checked, total)
+	}
+}
+
+func TestChecker_CheckContext_canceled(t *testing.T) {
+	mockProductSlugKey := "header3"
+
+	mockRecords := []csv.Record{
+		{Data: map[string]string{"header3": "slug-1"}},
+		{Data: map[string]string{"header3": "slug-2"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockPartner := &MockPartner{}
+	mockPartner.On("Login").Return(nil)
+	mockPartner.On("GetProduct", "slug-1").
+		Run(func(mock.Arguments) { cancel() }).
+		Return(&product.Product{}, nil)
+
+	c := &Checker{
+		records:        mockRecords,
+		partner:        mockPartner,
+		productSlugKey: mockProductSlugKey,
+	}
+
+	report, err := c.CheckContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("Checker.CheckContext() error = %v, want %v", err, context.Canceled)
+	}
+	if report.Checked != 1 {
+		t.Errorf("Checker.CheckContext() checked = %v, want %v", report.Checked, 1)
+	}
+	if want := []int{2}; !reflect.DeepEqual(report.Unchecked, want) {
+		t.Errorf("Checker.CheckContext() unchecked = %v, want %v", report.Unchecked, want)
+	}
+	mockPartner.AssertNotCalled(t, "GetProduct", "slug-2")
+}
+
+func TestChecker_CheckContext_partnerUnavailable(t *testing.T) {
+	mockProductSlugKey := "header3"
+
+	mockRecords := []csv.Record{
+		{Data: map[string]string{"header3": "slug-1"}},
+		{Data: map[string]string{"header3": "slug-2"}},
+	}
+
+	mockPartner := &MockPartner{}
+	mockPartner.On("Login").Return(nil)
+	mockPartner.On("GetProduct", mock.Anything).Return(nil, partner.ErrPartnerUnavailable)
+
+	c := &Checker{
+		records:        mockRecords,
+		partner:        mockPartner,
+		productSlugKey: mockProductSlugKey,
+	}
+
+	report, err := c.CheckContext(context.Background())
+	if err != nil {
+		t.Errorf("Checker.CheckContext() error = %v, wantErr %v", err, false)
+	}
+	if got := report.CountByKind()[KindPartnerUnavailable]; got != 2 {
+		t.Errorf("Checker.CheckContext() partner unavailable findings = %v, want %v", got, 2)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{name: "unauthorized", err: &partner.HTTPError{StatusCode: 401}, want: KindUnauthorized},
+		{name: "rate limited", err: &partner.HTTPError{StatusCode: 429}, want: KindRateLimited},
+		{name: "malformed response", err: &partner.DecodeError{}, want: KindMalformedResponse},
+		{name: "other error", err: &partner.HTTPError{StatusCode: 500}, want: KindPartnerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorKind(tt.err); got != tt.want {
+				t.Errorf("errorKind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_Slugs(t *testing.T) {
+	c := &Checker{
+		records: []csv.Record{
+			{Data: map[string]string{"header3": "slug-1"}},
+			{Data: map[string]string{"header3": "slug-2"}},
+			{Data: map[string]string{"header3": "slug-1"}},
+			{Data: map[string]string{"header3": ""}},
+			{Data: map[string]string{"header3": "slug-3"}},
+		},
+		productSlugKey: "header3",
+	}
+
+	if got, want := c.Slugs(), []string{"slug-1", "slug-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Checker.Slugs() = %v, want %v", got, want)
+	}
+}
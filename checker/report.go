@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// Kind tells what a Finding is about.
+type Kind string
+
+const (
+	KindPriceChanged       Kind = "price_changed"
+	KindStockLevelChanged  Kind = "stock_level_changed"
+	KindVariantNotFound    Kind = "variant_not_found"
+	KindPartnerError       Kind = "partner_error"
+	KindPartnerUnavailable Kind = "partner_unavailable"
+	KindDiscontinued       Kind = "product_discontinued"
+	KindUnauthorized       Kind = "unauthorized"
+	KindRateLimited        Kind = "rate_limited"
+	KindMalformedResponse  Kind = "malformed_response"
+	KindProductChanged     Kind = "product_changed"
+	KindVariantAdded       Kind = "variant_added"
+	KindVariantRemoved     Kind = "variant_removed"
+	KindNeedsReview        Kind = "needs_review"
+	KindResolved           Kind = "resolved"
+	KindRuleMatched        Kind = "rule_matched"
+)
+
+// Finding is a single thing a Check run noticed about a CSV row.
+type Finding struct {
+	Kind     Kind   `json:"kind"`
+	Partner  string `json:"partner,omitempty"`
+	Row      int    `json:"row"`
+	SKU      string `json:"sku"`
+	Slug     string `json:"slug"`
+	Variant  string `json:"variant"`
+	OldValue int    `json:"old_value"`
+	NewValue int    `json:"new_value"`
+	Message  string `json:"message,omitempty"`
+
+	// Rule is set on the findings of custom rules. Severity and Routes are
+	// set by custom rules, on their findings and on the ones they mark, and
+	// decide which notifiers get the finding.
+	Rule     string   `json:"rule,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Routes   []string `json:"routes,omitempty"`
+}
+
+// Report is the result of a Check run. A run that was stopped early still
+// returns a report, with the rows it didn't get to in Unchecked.
+type Report struct {
+	Partner    string    `json:"partner,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Checked    int       `json:"checked"`
+	Findings   []Finding `json:"findings"`
+	Unchecked  []int     `json:"unchecked,omitempty"`
+
+	// Products are the products fetched during the run, by slug.
+	Products map[string]*product.Product `json:"-"`
+
+	// Observations are the partner's variants of the rows that were found.
+	Observations []Observation `json:"-"`
+}
+
+// Observation is what the partner had for the variant of a row, next to
+// what the row had.
+type Observation struct {
+	Row     int
+	SKU     string
+	Slug    string
+	Variant product.Variant
+
+	OldPrice      int
+	OldStockLevel int
+	Data          map[string]string
+}
+
+// CountByKind returns how many findings of each kind the report has.
+func (r *Report) CountByKind() map[Kind]int {
+	res := map[Kind]int{}
+	for _, f := range r.Findings {
+		res[f.Kind]++
+	}
+	return res
+}
+
+func joinRows(rows []int) string {
+	s := make([]string, len(rows))
+	for i, r := range rows {
+		s[i] = strconv.Itoa(r)
+	}
+	return strings.Join(s, ", ")
+}
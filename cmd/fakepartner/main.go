@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andrysds/dropship-checker/fakepartner"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	fixtures := flag.String("fixtures", "fakepartner/testdata", "directory of <slug>.json product fixtures")
+	username := flag.String("username", "admin", "accepted login username")
+	password := flag.String("password", "admin", "accepted login password")
+	latency := flag.Duration("latency", 0, "latency added to every response")
+	errorRate := flag.Float64("error-rate", 0, "fraction of product requests that fail")
+	errorStatus := flag.Int("error-status", http.StatusInternalServerError, "status code of failing requests, -1 to hang until the client times out")
+	slugErrors := flag.String("slug-errors", "", "comma separated slug=status pairs that always fail")
+	tokenTTL := flag.Int("token-ttl", 0, "product requests a token is valid for, 0 for no expiry")
+	flag.Parse()
+
+	products, err := fakepartner.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatalln("[ERROR] [LoadFixtures]", err)
+	}
+
+	s := fakepartner.New(products)
+	s.Username = *username
+	s.Password = *password
+	s.Latency = *latency
+	s.ErrorRate = *errorRate
+	s.ErrorStatus = *errorStatus
+	s.TokenTTL = *tokenTTL
+	s.SlugErrors, err = parseSlugErrors(*slugErrors)
+	if err != nil {
+		log.Fatalln("[ERROR] [parsing slug errors]", err)
+	}
+
+	log.Printf("serving %d products on http://%s\n", len(products), *addr)
+	log.Printf("LOGIN_URL=\"http://%s%s\"\n", *addr, fakepartner.LoginPath)
+	log.Printf("GET_PRODUCT_BASE_URL=\"http://%s%s\"\n", *addr, fakepartner.ProductBaseURL)
+
+	log.Fatalln(http.ListenAndServe(*addr, s))
+}
+
+func parseSlugErrors(s string) (map[string]int, error) {
+	res := map[string]int{}
+	if s == "" {
+		return res, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, strconv.ErrSyntax
+		}
+
+		status, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		res[kv[0]] = status
+	}
+
+	return res, nil
+}
@@ -51,3 +51,25 @@ func NewCSV(file io.Reader) ([]Record, error) {
 
 	return res, nil
 }
+
+// Write writes records as a CSV file with the headers of CSV_HEADERS, the
+// format NewCSV reads.
+func Write(w io.Writer, records []Record) error {
+	headers := strings.Split(os.Getenv(headersEnvKey), ",")
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := make([]string, len(headers))
+		for i, h := range headers {
+			row[i] = r.Data[h]
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -61,3 +61,22 @@ func TestNewCSV(t *testing.T) {
 		})
 	}
 }
+
+func TestWrite(t *testing.T) {
+	os.Setenv(headersEnvKey, "header1,header2")
+	defer os.Unsetenv(headersEnvKey)
+
+	in := "header1,header2\ndata1,\"data, 2\"\ndata3,data4\n"
+	records, err := NewCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("NewCSV() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := Write(&b, records); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := b.String(); got != in {
+		t.Errorf("Write() = %q, want %q", got, in)
+	}
+}
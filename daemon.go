@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/metrics"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	checkScheduleEnvKey  = "CHECK_SCHEDULE"
+	metricsAddrEnvKey    = "METRICS_ADDR"
+	defaultCheckSchedule = "@hourly"
+)
+
+// daemon checks the CSV on a schedule with one partner session, so the
+// login and the product cache outlive single runs.
+type daemon struct {
+	partner *partner.Session
+	opts    checkOptions
+}
+
+func runDaemon(ctx context.Context, args []string) {
+	schedule := os.Getenv(checkScheduleEnvKey)
+	if schedule == "" {
+		schedule = defaultCheckSchedule
+	}
+
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	spec := fs.String("schedule", schedule, "cron expression of the check runs, like \"0 * * * *\" or \"@every 30m\"")
+	runNow := fs.Bool("now", false, "check right away, before the first scheduled run")
+	reportPath := fs.String("report", "", "write the JSON report of every run to this path")
+	baselinePath := fs.String("baseline", "", "report product changes since this snapshot file, then update it")
+	metricsAddr := fs.String("metrics-addr", os.Getenv(metricsAddrEnvKey), "serve Prometheus metrics on this address, like \":9090\"")
+	fs.Parse(args)
+
+	sched, err := cron.ParseStandard(*spec)
+	if err != nil {
+		log.Fatalln("[ERROR] [parsing schedule]", err)
+	}
+
+	live := newPartner(ctx)
+	defer closePartner(live)
+	s := partner.NewSession(metrics.InstrumentPartner(live, os.Getenv(checker.PartnerNameEnvKey)))
+	metrics.ObserveCache(s)
+
+	d := &daemon{
+		partner: s,
+		opts:    checkOptions{reportPath: *reportPath, baselinePath: *baselinePath, history: optionalHistoryStore()},
+	}
+	log.Printf("[INFO] daemon started; schedule: %s\n", *spec)
+
+	if *metricsAddr != "" {
+		srv := serveMetrics(*metricsAddr)
+		defer srv.Shutdown(context.Background())
+	}
+
+	if *runNow {
+		d.run(ctx)
+	}
+
+	// Runs happen one after the other in this loop, so they can't overlap.
+	// Scheduled times that pass during a run are skipped.
+	for ctx.Err() == nil {
+		next := sched.Next(time.Now())
+		log.Printf("[INFO] next run at %s\n", next.Format(time.RFC3339))
+
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+		case <-t.C:
+			d.run(ctx)
+			if missed := sched.Next(next); missed.Before(time.Now()) {
+				log.Printf("[WARN] run took longer than the schedule; skipped the run at %s\n", missed.Format(time.RFC3339))
+			}
+		}
+	}
+
+	log.Println("[INFO] daemon stopped")
+}
+
+// serveMetrics serves /metrics on addr in the background.
+func serveMetrics(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Println("[ERROR] [serving metrics]", err)
+		}
+	}()
+	log.Printf("[INFO] serving metrics; addr: %s\n", addr)
+	return srv
+}
+
+// run checks the CSV once. The run is not canceled with ctx: a SIGTERM lets
+// it finish, and only a second one exits right away.
+func (d *daemon) run(ctx context.Context) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("[INFO] stopping after the run in flight; signal again to exit now")
+			signal.Reset(os.Interrupt, syscall.SIGTERM)
+		case <-done:
+		}
+	}()
+
+	runCtx, cancel := withRunTimeout(context.Background())
+	defer cancel()
+
+	records, op, err := readRecords()
+	if err != nil {
+		log.Println("[ERROR] ["+op+"]", err)
+		alertFailure(runCtx, op, err)
+		return
+	}
+
+	if _, err := check(runCtx, checker.NewChecker(records, d.partner), d.opts); err != nil {
+		log.Println("[ERROR] [Check]", err)
+	}
+}
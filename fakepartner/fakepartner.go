@@ -0,0 +1,209 @@
+// Package fakepartner serves the partner API from JSON fixtures, so the
+// checker can run end-to-end without the real supplier.
+package fakepartner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	mathrand "math/rand"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/product"
+)
+
+const (
+	LoginPath      = "/login"
+	ProductBaseURL = "/product/"
+)
+
+// Timeout is an ErrorStatus that makes the server hang until the client
+// gives up instead of answering.
+const Timeout = -1
+
+type Server struct {
+	Products map[string]*product.Product
+	Username string
+	Password string
+
+	// Latency is added before every response.
+	Latency time.Duration
+
+	// ErrorRate is the fraction of product requests answered with
+	// ErrorStatus instead of the product.
+	ErrorRate   float64
+	ErrorStatus int
+
+	// SlugErrors always answers the given slugs with the status code.
+	SlugErrors map[string]int
+
+	// TokenTTL is how many product requests a token is valid for. Zero
+	// means tokens never expire.
+	TokenTTL int
+
+	mu     sync.Mutex
+	rand   *mathrand.Rand
+	tokens map[string]int
+}
+
+func New(products map[string]*product.Product) *Server {
+	return &Server{
+		Products:    products,
+		ErrorStatus: http.StatusInternalServerError,
+		rand:        mathrand.New(mathrand.NewSource(1)),
+		tokens:      map[string]int{},
+	}
+}
+
+// LoadFixtures reads every <slug>.json file in dir as a product.
+func LoadFixtures(dir string) (map[string]*product.Product, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	products := map[string]*product.Product{}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var p product.Product
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		products[strings.TrimSuffix(filepath.Base(path), ".json")] = &p
+	}
+
+	return products, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.Latency):
+		}
+	}
+
+	switch {
+	case r.URL.Path == LoginPath && r.Method == http.MethodPost:
+		s.login(w, r)
+	case strings.HasPrefix(r.URL.Path, ProductBaseURL) && r.Method == http.MethodGet:
+		s.getProduct(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	if body.Username != s.Username || body.Password != s.Password {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	token := newToken()
+	s.mu.Lock()
+	s.tokens[token] = s.TokenTTL
+	s.mu.Unlock()
+
+	writeData(w, map[string]string{"token": token})
+}
+
+func (s *Server) getProduct(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(r.Header.Get("Authorization")) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	slug := strings.TrimPrefix(r.URL.Path, ProductBaseURL)
+
+	status, ok := s.SlugErrors[slug]
+	if !ok && s.fail() {
+		status, ok = s.ErrorStatus, true
+	}
+	if ok {
+		if status == Timeout {
+			<-r.Context().Done()
+			return
+		}
+		writeError(w, status)
+		return
+	}
+
+	p, ok := s.Products[slug]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeData(w, p)
+}
+
+// authorize spends one request of the token's TTL.
+func (s *Server) authorize(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+
+	if s.TokenTTL == 0 {
+		return true
+	}
+
+	if remaining == 0 {
+		delete(s.tokens, token)
+		return false
+	}
+
+	s.tokens[token] = remaining - 1
+	return true
+}
+
+func (s *Server) fail() bool {
+	if s.ErrorRate == 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64() < s.ErrorRate
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status)})
+}
@@ -0,0 +1,137 @@
+package fakepartner_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/fakepartner"
+	"github.com/andrysds/dropship-checker/partner"
+)
+
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	t.Cleanup(func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+func newServer(t *testing.T) (*fakepartner.Server, *httptest.Server) {
+	products, err := fakepartner.LoadFixtures("testdata")
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+
+	s := fakepartner.New(products)
+	s.Username = "admin"
+	s.Password = "secret"
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	setenv(t, map[string]string{
+		"USERNAME":             "admin",
+		"PASSWORD":             "secret",
+		"LOGIN_URL":            ts.URL + fakepartner.LoginPath,
+		"GET_PRODUCT_BASE_URL": ts.URL + fakepartner.ProductBaseURL,
+		"RETRY_BASE_DELAY":     "1ms",
+		"STOCK_LEVEL_KEY":      "Stock Level",
+		"PRICE_KEY":            "Price",
+		"PRODUCT_SLUG_KEY":     "Product Slug",
+		"VARIANT_NAME_KEY":     "Variant Name",
+		"SKU_KEY":              "SKU",
+	})
+
+	return s, ts
+}
+
+func record(slug, variant, price, stockLevel, sku string) csv.Record {
+	return csv.Record{Data: map[string]string{
+		"Product Slug": slug,
+		"Variant Name": variant,
+		"Price":        price,
+		"Stock Level":  stockLevel,
+		"SKU":          sku,
+	}}
+}
+
+func TestServer_endToEnd(t *testing.T) {
+	s, _ := newServer(t)
+	s.SlugErrors = map[string]int{"broken-slug": http.StatusInternalServerError}
+
+	records := []csv.Record{
+		record("sample-slug", "red", "Rp10,000", "0", "SKU-1"),
+		record("sample-slug", "blue", "11000", "2", "SKU-2"),
+		record("other-slug", "default", "25000", "1", "SKU-3"),
+		record("missing-slug", "default", "1000", "1", "SKU-4"),
+		record("broken-slug", "default", "1000", "1", "SKU-5"),
+	}
+
+	c := checker.NewChecker(records, newPartner(t))
+	report, err := c.CheckContext(context.Background())
+	if err != nil {
+		t.Fatalf("Checker.CheckContext() error = %v", err)
+	}
+
+	want := map[checker.Kind]int{
+		checker.KindPriceChanged: 1,
+		checker.KindDiscontinued: 1,
+		checker.KindPartnerError: 1,
+	}
+	got := report.CountByKind()
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("findings of kind %v = %v, want %v", kind, got[kind], n)
+		}
+	}
+	if report.Checked != len(records) {
+		t.Errorf("Report.Checked = %v, want %v", report.Checked, len(records))
+	}
+}
+
+func TestServer_tokenExpiry(t *testing.T) {
+	s, _ := newServer(t)
+	s.TokenTTL = 1
+
+	records := []csv.Record{
+		record("sample-slug", "red", "10000", "0", "SKU-1"),
+		record("other-slug", "default", "25000", "1", "SKU-3"),
+	}
+
+	c := checker.NewChecker(records, newPartner(t))
+	report, err := c.CheckContext(context.Background())
+	if err != nil {
+		t.Fatalf("Checker.CheckContext() error = %v", err)
+	}
+
+	if got := report.CountByKind()[checker.KindUnauthorized]; got != 1 {
+		t.Errorf("findings of kind %v = %v, want %v", checker.KindUnauthorized, got, 1)
+	}
+}
+
+func TestServer_wrongCredentials(t *testing.T) {
+	newServer(t)
+	os.Setenv("PASSWORD", "wrong")
+
+	p := newPartner(t)
+	if err := p.Login(); err == nil {
+		t.Errorf("Partner.Login() error = nil, want unauthorized")
+	}
+}
+
+func newPartner(t *testing.T) *partner.Partner {
+	t.Helper()
+	p, err := partner.NewPartner(nil)
+	if err != nil {
+		t.Fatalf("NewPartner() error = %v", err)
+	}
+	return p
+}
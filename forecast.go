@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andrysds/dropship-checker/forecast"
+	"github.com/andrysds/dropship-checker/history"
+)
+
+func runForecast(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("forecast", flag.ExitOnError)
+	days := fs.Float64("days", 7, "list SKUs projected to run out within this many days")
+	lookback := fs.Duration("lookback", 14*24*time.Hour, "only use stock history this recent")
+	partner := fs.String("partner", "", "only this partner")
+	fs.Parse(args)
+
+	now := time.Now()
+	observations, err := historyStore().Query(history.Filter{Partner: *partner, Since: now.Add(-*lookback)})
+	if err != nil {
+		log.Fatalln("[ERROR] [querying history]", err)
+	}
+
+	forecasts := forecast.RunningOut(forecast.Estimate(observations), now, *days)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTNER\tSKU\tSLUG\tVARIANT\tSTOCK\tSOLD/DAY\tSTOCKOUT\tDAYS LEFT")
+	for _, f := range forecasts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%.1f\t%s\t%.1f\n",
+			f.Partner, f.SKU, f.Slug, f.Variant, f.Stock, f.Rate,
+			f.StockoutAt.Format("2006-01-02 15:04"), f.DaysLeft(now))
+	}
+	w.Flush()
+}
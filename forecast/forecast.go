@@ -0,0 +1,152 @@
+// Package forecast projects when variants run out of stock from their
+// stock history.
+package forecast
+
+import (
+	"sort"
+	"time"
+
+	"github.com/andrysds/dropship-checker/history"
+)
+
+// Forecast is the projected stockout of a SKU. StockoutAt is zero when the
+// stock isn't going down.
+type Forecast struct {
+	Partner    string
+	SKU        string
+	Slug       string
+	Variant    string
+	Stock      int
+	LastSeen   time.Time
+	Rate       float64 // units sold per day
+	StockoutAt time.Time
+	Samples    int
+}
+
+// DaysLeft returns how many days are left until the projected stockout
+// from now, or -1 when no stockout is projected.
+func (f Forecast) DaysLeft(now time.Time) float64 {
+	if f.StockoutAt.IsZero() {
+		return -1
+	}
+	d := f.StockoutAt.Sub(now).Hours() / 24
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// key tells the series of a reading apart by its SKU, or by its slug and
+// variant when the row has no SKU.
+type key struct {
+	partner string
+	sku     string
+	slug    string
+	variant string
+}
+
+func keyOf(o history.Observation) key {
+	if o.SKU != "" {
+		return key{partner: o.Partner, sku: o.SKU}
+	}
+	return key{partner: o.Partner, slug: o.Slug, variant: o.Variant}
+}
+
+// Estimate fits a line through the stock of every SKU since its last
+// restock and projects when it reaches zero. Observations without stock
+// and suspicious ones are ignored.
+func Estimate(observations []history.Observation) []Forecast {
+	series := map[key][]history.Observation{}
+	var keys []key
+	for _, o := range observations {
+		if o.Stock == nil || o.Suspicious {
+			continue
+		}
+		k := keyOf(o)
+		if _, ok := series[k]; !ok {
+			keys = append(keys, k)
+		}
+		series[k] = append(series[k], o)
+	}
+
+	res := make([]Forecast, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, estimate(series[k]))
+	}
+	return res
+}
+
+func estimate(series []history.Observation) Forecast {
+	sort.SliceStable(series, func(i, j int) bool { return series[i].Time.Before(series[j].Time) })
+
+	// a restock starts a new depletion run
+	start := 0
+	for i := 1; i < len(series); i++ {
+		if *series[i].Stock > *series[i-1].Stock {
+			start = i
+		}
+	}
+	series = series[start:]
+
+	last := series[len(series)-1]
+	f := Forecast{
+		Partner:  last.Partner,
+		SKU:      last.SKU,
+		Slug:     last.Slug,
+		Variant:  last.Variant,
+		Stock:    *last.Stock,
+		LastSeen: last.Time,
+		Samples:  len(series),
+	}
+
+	slope, ok := slopePerDay(series)
+	if !ok || slope >= 0 {
+		return f
+	}
+
+	f.Rate = -slope
+	if f.Stock <= 0 {
+		f.StockoutAt = last.Time
+		return f
+	}
+	f.StockoutAt = last.Time.Add(time.Duration(float64(f.Stock) / f.Rate * float64(24*time.Hour)))
+	return f
+}
+
+// slopePerDay is the least squares slope of stock over time.
+func slopePerDay(series []history.Observation) (float64, bool) {
+	if len(series) < 2 {
+		return 0, false
+	}
+
+	t0 := series[0].Time
+	var sumX, sumY, sumXY, sumXX float64
+	for _, o := range series {
+		x := o.Time.Sub(t0).Hours() / 24
+		y := float64(*o.Stock)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(series))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// RunningOut returns the forecasts with a stockout within days of now,
+// soonest first.
+func RunningOut(forecasts []Forecast, now time.Time, days float64) []Forecast {
+	var res []Forecast
+	for _, f := range forecasts {
+		if left := f.DaysLeft(now); left >= 0 && left <= days {
+			res = append(res, f)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].StockoutAt.Before(res[j].StockoutAt) })
+	return res
+}
@@ -0,0 +1,91 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/history"
+)
+
+func TestEstimate(t *testing.T) {
+	t0 := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	observation := func(sku string, days int, stock int) history.Observation {
+		return history.Observation{Time: t0.Add(time.Duration(days) * day), SKU: sku, Stock: history.Int(stock)}
+	}
+
+	observations := []history.Observation{
+		// sells 10 a day
+		observation("SKU-1", 0, 100),
+		observation("SKU-1", 1, 90),
+		observation("SKU-1", 2, 80),
+		// restocked on day 2, then sells 5 a day
+		observation("SKU-2", 0, 10),
+		observation("SKU-2", 1, 0),
+		observation("SKU-2", 2, 50),
+		observation("SKU-2", 3, 45),
+		// steady
+		observation("SKU-3", 0, 30),
+		observation("SKU-3", 1, 30),
+		// no stock data
+		{Time: t0, SKU: "SKU-4", Price: history.Int(1000)},
+	}
+
+	forecasts := Estimate(observations)
+	if len(forecasts) != 3 {
+		t.Fatalf("Estimate() returned %d forecasts, want %d", len(forecasts), 3)
+	}
+
+	tests := []struct {
+		sku            string
+		wantRate       float64
+		wantStockoutAt time.Time
+	}{
+		{sku: "SKU-1", wantRate: 10, wantStockoutAt: t0.Add(10 * day)},
+		{sku: "SKU-2", wantRate: 5, wantStockoutAt: t0.Add(12 * day)},
+		{sku: "SKU-3", wantRate: 0, wantStockoutAt: time.Time{}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.sku, func(t *testing.T) {
+			f := forecasts[i]
+			if f.SKU != tt.sku {
+				t.Fatalf("Forecast.SKU = %v, want %v", f.SKU, tt.sku)
+			}
+			if f.Rate != tt.wantRate {
+				t.Errorf("Forecast.Rate = %v, want %v", f.Rate, tt.wantRate)
+			}
+			if !f.StockoutAt.Equal(tt.wantStockoutAt) {
+				t.Errorf("Forecast.StockoutAt = %v, want %v", f.StockoutAt, tt.wantStockoutAt)
+			}
+		})
+	}
+
+	soon := RunningOut(forecasts, t0.Add(3*day), 7)
+	if len(soon) != 1 || soon[0].SKU != "SKU-1" {
+		t.Errorf("RunningOut() = %v, want only SKU-1", soon)
+	}
+}
+
+func TestEstimate_withoutSKU(t *testing.T) {
+	t0 := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	observation := func(variant string, days int, stock int) history.Observation {
+		return history.Observation{Time: t0.Add(time.Duration(days) * 24 * time.Hour), Slug: "red-shirt", Variant: variant, Stock: history.Int(stock)}
+	}
+
+	forecasts := Estimate([]history.Observation{
+		observation("L", 0, 100),
+		observation("M", 0, 10),
+		observation("L", 1, 90),
+		observation("M", 1, 8),
+	})
+	if len(forecasts) != 2 {
+		t.Fatalf("Estimate() returned %d forecasts, want %d", len(forecasts), 2)
+	}
+	if forecasts[0].Variant != "L" || forecasts[0].Rate != 10 {
+		t.Errorf("Estimate() first forecast = %+v, want variant L selling 10 a day", forecasts[0])
+	}
+	if forecasts[1].Variant != "M" || forecasts[1].Rate != 2 {
+		t.Errorf("Estimate() second forecast = %+v, want variant M selling 2 a day", forecasts[1])
+	}
+}
@@ -3,6 +3,12 @@ module github.com/andrysds/dropship-checker
 go 1.16
 
 require (
+	github.com/prometheus/client_golang v1.12.2
+	github.com/prometheus/client_model v0.2.0
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/stretchr/objx v0.3.0 // indirect
 	github.com/stretchr/testify v1.7.1
 	github.com/subosito/gotenv v1.4.0
+	golang.org/x/sys v0.7.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
 )
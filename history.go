@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andrysds/dropship-checker/history"
+)
+
+const historyPathEnvKey = "HISTORY_PATH"
+
+func historyStore() *history.Store {
+	store := optionalHistoryStore()
+	if store == nil {
+		log.Fatalln("[ERROR] [history]", historyPathEnvKey, "is not set")
+	}
+	return store
+}
+
+// optionalHistoryStore returns the store of HISTORY_PATH, or nil when it is
+// not set. A process shares one store, so its appends and queries are
+// serialized.
+func optionalHistoryStore() *history.Store {
+	path := os.Getenv(historyPathEnvKey)
+	if path == "" {
+		return nil
+	}
+	return history.NewStore(path)
+}
+
+func runHistory(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		log.Fatalln("[ERROR] [history] usage: history timeline|export [flags]")
+	}
+
+	fs := flag.NewFlagSet("history "+args[0], flag.ExitOnError)
+	sku := fs.String("sku", "", "only this SKU")
+	slug := fs.String("slug", "", "only this product slug")
+	partner := fs.String("partner", "", "only this partner")
+	since := fs.Duration("since", 0, "only observations newer than this, e.g. 720h")
+	out := fs.String("o", "", "export: write the CSV to this path instead of stdout")
+	fs.Parse(args[1:])
+
+	f := history.Filter{Partner: *partner, SKU: *sku, Slug: *slug}
+	if *since > 0 {
+		f.Since = time.Now().Add(-*since)
+	}
+
+	observations, err := historyStore().Query(f)
+	if err != nil {
+		log.Fatalln("[ERROR] [querying history]", err)
+	}
+
+	switch args[0] {
+	case "timeline":
+		printTimeline(observations)
+	case "export":
+		w := os.Stdout
+		if *out != "" {
+			if w, err = os.Create(*out); err != nil {
+				log.Fatalln("[ERROR] [creating export file]", err)
+			}
+			defer w.Close()
+		}
+		if err := history.WriteCSV(w, observations); err != nil {
+			log.Fatalln("[ERROR] [exporting history]", err)
+		}
+	default:
+		log.Fatalln("[ERROR] [history] unknown subcommand:", args[0])
+	}
+}
+
+func printTimeline(observations []history.Observation) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tPARTNER\tSKU\tSLUG\tVARIANT\tPRICE\tSTOCK\tSTOCK LEVEL")
+	for _, o := range observations {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			o.Time.Format("2006-01-02 15:04"), o.Partner, o.SKU, o.Slug, o.Variant,
+			optional(o.Price), optional(o.Stock), optional(o.StockLevel))
+	}
+	w.Flush()
+}
+
+func optional(v *int) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(*v)
+}
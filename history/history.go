@@ -0,0 +1,208 @@
+// Package history keeps every price and stock observation of the partner in
+// an append-only JSON lines file.
+package history
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+// Observation is what the partner had for a variant at a point in time.
+// Price, Stock and StockLevel are nil when the source didn't tell.
+type Observation struct {
+	Time       time.Time `json:"time"`
+	Partner    string    `json:"partner,omitempty"`
+	Slug       string    `json:"slug"`
+	Variant    string    `json:"variant"`
+	SKU        string    `json:"sku"`
+	Price      *int      `json:"price,omitempty"`
+	Stock      *int      `json:"stock,omitempty"`
+	StockLevel *int      `json:"stock_level,omitempty"`
+
+	// Suspicious readings are kept for review but left out of baselines.
+	Suspicious bool `json:"suspicious,omitempty"`
+}
+
+// FromReport returns the observations of a check run.
+func FromReport(report *checker.Report) []Observation {
+	res := make([]Observation, 0, len(report.Observations))
+	for _, o := range report.Observations {
+		v := o.Variant
+		res = append(res, Observation{
+			Time:       report.StartedAt,
+			Partner:    report.Partner,
+			Slug:       o.Slug,
+			Variant:    v.Name,
+			SKU:        o.SKU,
+			Price:      Int(v.Price),
+			Stock:      Int(v.Stock),
+			StockLevel: Int(v.StockLevel()),
+		})
+	}
+	return res
+}
+
+// Int returns a pointer to v, for the optional Observation fields.
+func Int(v int) *int {
+	return &v
+}
+
+// Store is a history file. It is safe for concurrent use within a process.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append adds observations to the end of the history file.
+func (s *Store) Append(observations ...Observation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, o := range observations {
+		if err := enc.Encode(o); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Import appends the observations that aren't stored yet, so importing the
+// same source twice doesn't duplicate them. It returns how many were added.
+func (s *Store) Import(observations []Observation) (int, error) {
+	existing, err := s.Query(Filter{})
+	if err != nil {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	for _, o := range existing {
+		seen[o.key()] = true
+	}
+
+	var added []Observation
+	for _, o := range observations {
+		if !seen[o.key()] {
+			seen[o.key()] = true
+			added = append(added, o)
+		}
+	}
+
+	return len(added), s.Append(added...)
+}
+
+func (o Observation) key() string {
+	return strings.Join([]string{
+		o.Time.UTC().Format(time.RFC3339Nano), o.Partner, o.Slug, o.Variant, o.SKU,
+		formatInt(o.Price), formatInt(o.Stock), formatInt(o.StockLevel),
+	}, "|")
+}
+
+// Filter selects observations. Empty fields match everything.
+type Filter struct {
+	Partner string
+	SKU     string
+	Slug    string
+	Since   time.Time
+}
+
+func (f Filter) match(o Observation) bool {
+	return (f.Partner == "" || f.Partner == o.Partner) &&
+		(f.SKU == "" || f.SKU == o.SKU) &&
+		(f.Slug == "" || f.Slug == o.Slug) &&
+		(f.Since.IsZero() || !o.Time.Before(f.Since))
+}
+
+// Query returns the observations matching f, oldest first. A missing
+// history file is an empty history.
+func (s *Store) Query(f Filter) ([]Observation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var res []Observation
+	dec := json.NewDecoder(file)
+	for {
+		var o Observation
+		err := dec.Decode(&o)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.match(o) {
+			res = append(res, o)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	return res, nil
+}
+
+var csvHeaders = []string{"Time", "Partner", "Slug", "Variant", "SKU", "Price", "Stock", "Stock Level"}
+
+// WriteCSV writes observations as CSV. Unknown values are left empty.
+func WriteCSV(w io.Writer, observations []Observation) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeaders); err != nil {
+		return err
+	}
+
+	for _, o := range observations {
+		cw.Write([]string{
+			o.Time.Format(time.RFC3339),
+			o.Partner,
+			o.Slug,
+			o.Variant,
+			o.SKU,
+			formatInt(o.Price),
+			formatInt(o.Stock),
+			formatInt(o.StockLevel),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
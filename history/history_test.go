@@ -0,0 +1,121 @@
+package history
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestFromReport(t *testing.T) {
+	startedAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	report := &checker.Report{
+		Partner:   "sample partner",
+		StartedAt: startedAt,
+		Observations: []checker.Observation{
+			{Row: 1, SKU: "SKU-1", Slug: "sample-slug", Variant: product.Variant{Name: "red", Price: 1000, Stock: 30}},
+		},
+	}
+
+	want := []Observation{{
+		Time:       startedAt,
+		Partner:    "sample partner",
+		Slug:       "sample-slug",
+		Variant:    "red",
+		SKU:        "SKU-1",
+		Price:      Int(1000),
+		Stock:      Int(30),
+		StockLevel: Int(product.HighStock),
+	}}
+
+	if got := FromReport(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("FromReport() = %v, want %v", got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	if got, err := s.Query(Filter{}); err != nil || got != nil {
+		t.Errorf("Store.Query() of a missing file = %v, %v, want nil, nil", got, err)
+	}
+
+	t0 := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	observations := []Observation{
+		{Time: t0.Add(time.Hour), SKU: "SKU-1", Price: Int(1100)},
+		{Time: t0, SKU: "SKU-1", Price: Int(1000)},
+		{Time: t0, SKU: "SKU-2", Stock: Int(5)},
+	}
+	if err := s.Append(observations[:2]...); err != nil {
+		t.Fatalf("Store.Append() error = %v", err)
+	}
+	if err := s.Append(observations[2]); err != nil {
+		t.Fatalf("Store.Append() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []Observation
+	}{
+		{
+			name:   "by sku, oldest first",
+			filter: Filter{SKU: "SKU-1"},
+			want:   []Observation{observations[1], observations[0]},
+		},
+		{
+			name:   "since",
+			filter: Filter{Since: t0.Add(time.Minute)},
+			want:   []Observation{observations[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Store.Query() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Store.Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	observations := []Observation{
+		{Time: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC), Slug: "sample-slug", Variant: "red", SKU: "SKU-1", Price: Int(1000)},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, observations); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "Time,Partner,Slug,Variant,SKU,Price,Stock,Stock Level\n" +
+		"2022-05-01T10:00:00Z,,sample-slug,red,SKU-1,1000,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestStore_Import(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	observations := []Observation{
+		{Time: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC), SKU: "SKU-1", Price: Int(1000)},
+	}
+
+	for i, want := range []int{1, 0} {
+		added, err := s.Import(observations)
+		if err != nil {
+			t.Fatalf("Store.Import() error = %v", err)
+		}
+		if added != want {
+			t.Errorf("Store.Import() #%d added = %v, want %v", i+1, added, want)
+		}
+	}
+}
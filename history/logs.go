@@ -0,0 +1,58 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// logTimeLayout is the timestamp the standard logger writes with
+// log.LstdFlags, optionally followed by microseconds.
+const logTimeLayout = "2006/01/02 15:04:05"
+
+var (
+	logLinePattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2})(\.\d+)? (.*)$`)
+
+	// Slug and variant were added to the lines later; older logs end at
+	// the SKU.
+	priceChangePattern      = regexp.MustCompile(`^\[WARN\] price change detected; row: \d+; new price: (-?\d+); sku: (.*?)(?:; slug: (.*?); variant: (.*))?$`)
+	stockLevelChangePattern = regexp.MustCompile(`^\[WARN\] stock level change detected; row: \d+; new stock level: (-?\d+); sku: (.*?)(?:; slug: (.*?); variant: (.*))?$`)
+)
+
+// ParseLog reads the price and stock level changes Checker.Check logged
+// into observations. Log timestamps are read in loc. Lines of older logs
+// have no slug and variant, so their observations only have the SKU. Other
+// lines are skipped.
+func ParseLog(r io.Reader, partner string, loc *time.Location) ([]Observation, error) {
+	var res []Observation
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := logLinePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		t, err := time.ParseInLocation(logTimeLayout, m[1]+m[2], loc)
+		if err != nil {
+			continue
+		}
+
+		o := Observation{Time: t, Partner: partner}
+		if p := priceChangePattern.FindStringSubmatch(m[3]); p != nil {
+			price, _ := strconv.Atoi(p[1])
+			o.Price, o.SKU, o.Slug, o.Variant = Int(price), p[2], p[3], p[4]
+		} else if p := stockLevelChangePattern.FindStringSubmatch(m[3]); p != nil {
+			level, _ := strconv.Atoi(p[1])
+			o.StockLevel, o.SKU, o.Slug, o.Variant = Int(level), p[2], p[3], p[4]
+		} else {
+			continue
+		}
+
+		res = append(res, o)
+	}
+
+	return res, scanner.Err()
+}
@@ -0,0 +1,50 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLog(t *testing.T) {
+	logs := strings.Join([]string{
+		"2022/05/01 10:00:00 starting...",
+		"2022/05/01 10:00:01 [WARN] price change detected; row: 2; new price: 12000; sku: SKU-2",
+		"2022/05/01 10:00:02.123456 [WARN] stock level change detected; row: 3; new stock level: 0; sku: SKU 3",
+		"2022/05/01 10:00:03 [WARN] price change detected; row: 4; new price: 9000; sku: SKU-4; slug: red-shirt; variant: L",
+		"2022/05/01 10:00:03 [ERROR] [GetProduct] got this status code: 500",
+		"not a log line",
+	}, "\n")
+
+	want := []Observation{
+		{
+			Time:    time.Date(2022, 5, 1, 10, 0, 1, 0, time.UTC),
+			Partner: "sample partner",
+			SKU:     "SKU-2",
+			Price:   Int(12000),
+		},
+		{
+			Time:       time.Date(2022, 5, 1, 10, 0, 2, 123456000, time.UTC),
+			Partner:    "sample partner",
+			SKU:        "SKU 3",
+			StockLevel: Int(0),
+		},
+		{
+			Time:    time.Date(2022, 5, 1, 10, 0, 3, 0, time.UTC),
+			Partner: "sample partner",
+			SKU:     "SKU-4",
+			Slug:    "red-shirt",
+			Variant: "L",
+			Price:   Int(9000),
+		},
+	}
+
+	got, err := ParseLog(strings.NewReader(logs), "sample partner", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseLog() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLog() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/history"
+)
+
+func runImportLogs(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("import-logs", flag.ExitOnError)
+	partner := fs.String("partner", os.Getenv(checker.PartnerNameEnvKey), "partner the logs are about")
+	tz := fs.String("tz", "Local", "time zone the log timestamps are in")
+	fs.Parse(args)
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		log.Fatalln("[ERROR] [loading time zone]", err)
+	}
+
+	var observations []history.Observation
+	for _, path := range fs.Args() {
+		o, err := parseLogFile(path, *partner, loc)
+		if err != nil {
+			log.Fatalln("[ERROR] [parsing log file]", path, err)
+		}
+		log.Printf("log file: %s; observations: %d\n", path, len(o))
+		observations = append(observations, o...)
+	}
+
+	added, err := historyStore().Import(observations)
+	if err != nil {
+		log.Fatalln("[ERROR] [importing history]", err)
+	}
+	log.Printf("observations imported: %d; already in history: %d\n", added, len(observations)-added)
+}
+
+// parseLogFile parses a checker log file, gunzipping rotated .gz files.
+func parseLogFile(path, partner string, loc *time.Location) ([]history.Observation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return history.ParseLog(r, partner, loc)
+}
@@ -1,44 +1,136 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/andrysds/dropship-checker/checker"
 	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/metrics"
 	"github.com/andrysds/dropship-checker/partner"
 	"github.com/subosito/gotenv"
 )
 
-const csvPathEnvKey = "CSV_PATH"
+const (
+	csvPathEnvKey    = "CSV_PATH"
+	runTimeoutEnvKey = "RUN_TIMEOUT"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string)
+
+	// longRunning commands apply RUN_TIMEOUT to every run themselves.
+	longRunning bool
+}
+
+var commands = []command{
+	{name: "check", usage: "check the CSV against the partner (default)", run: runCheck},
+	{name: "snapshot", usage: "save the partner's products of the CSV to a snapshot file", run: runSnapshot},
+	{name: "history", usage: "show (timeline) or export (export) the price and stock history", run: runHistory},
+	{name: "import-logs", usage: "backfill the history from checker log files", run: runImportLogs},
+	{name: "forecast", usage: "list SKUs projected to run out of stock soon", run: runForecast},
+	{name: "daemon", usage: "keep running and check on a cron schedule", run: runDaemon, longRunning: true},
+	{name: "serve", usage: "serve an HTTP API to start checks and read their findings", run: runServe, longRunning: true},
+	{name: "review", usage: "accept or reject the changes of a check, then write them to a CSV", run: runReview, longRunning: true},
+}
 
 func main() {
 	envPath := flag.String("env", ".env", "your env file path")
+	flag.Usage = usage
 	flag.Parse()
 
 	log.Println("starting...")
 
 	gotenv.Load(*envPath)
 
-	csvPath := os.Getenv(csvPathEnvKey)
-	f, err := os.Open(csvPath)
-	if err != nil {
-		log.Fatalln("[ERROR] [opening csv file]", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	name, args := "check", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if !cmd.longRunning {
+				var cancel context.CancelFunc
+				ctx, cancel = withRunTimeout(ctx)
+				defer cancel()
+			}
+			cmd.run(ctx, args)
+			log.Println("exiting...")
+			return
+		}
 	}
 
-	r, err := csv.NewCSV(f)
+	usage()
+	os.Exit(2)
+}
+
+// withRunTimeout limits ctx to RUN_TIMEOUT when it is set.
+func withRunTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if runTimeout, err := time.ParseDuration(os.Getenv(runTimeoutEnvKey)); err == nil && runTimeout > 0 {
+		return context.WithTimeout(ctx, runTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: %s [-env path] [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func loadRecords(ctx context.Context) []csv.Record {
+	r, op, err := readRecords()
 	if err != nil {
-		log.Fatalln("[ERROR] [NewCSV]", err)
+		fatal(ctx, op, err)
 	}
+	return r
+}
 
-	p := partner.NewPartner()
+// readRecords reads the CSV of CSV_PATH. On failure op tells which step
+// failed.
+func readRecords() (r []csv.Record, op string, err error) {
+	f, err := os.Open(os.Getenv(csvPathEnvKey))
+	if err != nil {
+		return nil, "opening csv file", err
+	}
+	defer f.Close()
 
-	c := checker.NewChecker(r, p)
+	r, err = csv.NewCSV(f)
+	if err != nil {
+		return nil, "NewCSV", err
+	}
+	return r, "", nil
+}
 
-	if err := c.Check(); err != nil {
-		log.Fatalln("[ERROR] [Check]", err)
+// newPartner sets up the partner of the env, with its HTTP requests counted
+// in the metrics, or exits when its cassette can't be set up.
+func newPartner(ctx context.Context) *partner.Partner {
+	p, err := partner.NewPartner(metrics.InstrumentTransport(os.Getenv(checker.PartnerNameEnvKey), nil))
+	if err != nil {
+		fatal(ctx, "setting up partner", err)
 	}
+	return p
+}
 
-	log.Println("exiting...")
+// closePartner writes the cassette of a recording partner.
+func closePartner(p *partner.Partner) {
+	if err := p.Close(); err != nil {
+		log.Println("[ERROR] [saving cassette]", err)
+	}
 }
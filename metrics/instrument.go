@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	RunsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "dropship_check_runs_total",
+		Help: "Check runs by partner and result (ok or error).",
+	}, []string{"partner", "result"})
+	RunDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dropship_check_run_duration_seconds",
+		Help:    "Duration of check runs.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"partner"})
+	RowsChecked = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "dropship_rows_checked_total",
+		Help: "CSV rows checked against the partner.",
+	}, []string{"partner"})
+	FindingsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "dropship_findings_total",
+		Help: "Findings of check runs by partner and kind.",
+	}, []string{"partner", "kind"})
+
+	CallDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dropship_partner_call_duration_seconds",
+		Help:    "Latency of partner calls, retries included.",
+		Buckets: DefaultBuckets,
+	}, []string{"partner", "operation"})
+	LoginAttemptsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "dropship_partner_login_attempts_total",
+		Help: "Partner logins by result (ok or error).",
+	}, []string{"partner", "result"})
+
+	RequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dropship_partner_request_duration_seconds",
+		Help:    "Latency of every HTTP request to the partner.",
+		Buckets: DefaultBuckets,
+	}, []string{"partner", "method"})
+	ResponsesTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "dropship_partner_responses_total",
+		Help: "HTTP requests to the partner by status code, or \"error\" when there was no response.",
+	}, []string{"partner", "method", "code"})
+
+	CacheHitRatio = factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "dropship_product_cache_hit_ratio",
+		Help: "Share of product lookups served from the cache.",
+	}, cacheHitRatio)
+)
+
+// ObserveRun records a check run and its report.
+func ObserveRun(report *checker.Report, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	RunsTotal.WithLabelValues(report.Partner, result).Inc()
+	if !report.FinishedAt.IsZero() {
+		RunDuration.WithLabelValues(report.Partner).Observe(report.FinishedAt.Sub(report.StartedAt).Seconds())
+	}
+	RowsChecked.WithLabelValues(report.Partner).Add(float64(report.Checked))
+	for kind, n := range report.CountByKind() {
+		FindingsTotal.WithLabelValues(report.Partner, string(kind)).Add(float64(n))
+	}
+}
+
+var cache struct {
+	mu    sync.Mutex
+	stats func() (hits, misses int)
+}
+
+// ObserveCache makes the cache stats of c the cache hit ratio.
+func ObserveCache(c interface{ CacheStats() (hits, misses int) }) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.stats = c.CacheStats
+}
+
+func cacheHitRatio() float64 {
+	cache.mu.Lock()
+	stats := cache.stats
+	cache.mu.Unlock()
+
+	if stats == nil {
+		return 0
+	}
+	hits, misses := stats()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// InstrumentTransport counts and times every request next sends to the
+// partner called name, so retries and failed attempts show up too. A nil
+// next is http.DefaultTransport.
+func InstrumentTransport(name string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		res, err := next.RoundTrip(req)
+		RequestDuration.WithLabelValues(name, req.Method).Observe(time.Since(start).Seconds())
+
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(res.StatusCode)
+		}
+		ResponsesTotal.WithLabelValues(name, req.Method, code).Inc()
+		return res, err
+	})
+}
+
+// Partner times the calls of a partner and counts its logins.
+type Partner struct {
+	partner checker.ContextPartner
+	name    string
+}
+
+func InstrumentPartner(p checker.ContextPartner, name string) *Partner {
+	return &Partner{partner: p, name: name}
+}
+
+func (p *Partner) Login() error {
+	return p.LoginContext(context.Background())
+}
+
+func (p *Partner) LoginContext(ctx context.Context) error {
+	start := time.Now()
+	err := p.partner.LoginContext(ctx)
+	CallDuration.WithLabelValues(p.name, "login").Observe(time.Since(start).Seconds())
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	LoginAttemptsTotal.WithLabelValues(p.name, result).Inc()
+	return err
+}
+
+func (p *Partner) GetProduct(slug string) (*product.Product, error) {
+	return p.GetProductContext(context.Background(), slug)
+}
+
+func (p *Partner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	start := time.Now()
+	prod, err := p.partner.GetProductContext(ctx, slug)
+	CallDuration.WithLabelValues(p.name, "get_product").Observe(time.Since(start).Seconds())
+	return prod, err
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/partnertest"
+	"github.com/andrysds/dropship-checker/product"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sampleCount returns how many values h observed.
+func sampleCount(t *testing.T, h prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	if err := h.(prometheus.Metric).Write(m); err != nil {
+		t.Fatalf("Metric.Write() error = %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestPartner(t *testing.T) {
+	inner := partnertest.NewPartner(map[string]*product.Product{"sample-slug": {Name: "sample name"}})
+	p := InstrumentPartner(inner, "instrumented")
+
+	p.GetProduct("sample-slug")
+	p.Login()
+	p.GetProduct("sample-slug")
+	p.GetProduct("missing-slug")
+
+	if got := testutil.ToFloat64(LoginAttemptsTotal.WithLabelValues("instrumented", "ok")); got != 1 {
+		t.Errorf("login attempts = %v, want 1", got)
+	}
+	if got := sampleCount(t, CallDuration.WithLabelValues("instrumented", "get_product")); got != 3 {
+		t.Errorf("timed product calls = %v, want 3", got)
+	}
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	codes := []int{http.StatusServiceUnavailable, http.StatusOK}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(codes[0])
+		codes = codes[1:]
+	}))
+	defer ts.Close()
+
+	c := &http.Client{Transport: InstrumentTransport("transported", nil)}
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("Client.Get() error = %v", err)
+		}
+		res.Body.Close()
+	}
+
+	failing := promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("sample error")
+	})
+	c = &http.Client{Transport: InstrumentTransport("transported", failing)}
+	c.Get(ts.URL)
+
+	for _, code := range []string{"503", "200", "error"} {
+		if got := testutil.ToFloat64(ResponsesTotal.WithLabelValues("transported", http.MethodGet, code)); got != 1 {
+			t.Errorf("responses with code %v = %v, want 1", code, got)
+		}
+	}
+	if got := sampleCount(t, RequestDuration.WithLabelValues("transported", http.MethodGet)); got != 3 {
+		t.Errorf("timed requests = %v, want 3", got)
+	}
+}
+
+func TestObserveRun(t *testing.T) {
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	report := &checker.Report{
+		Partner:    "observed",
+		StartedAt:  start,
+		FinishedAt: start.Add(time.Minute),
+		Checked:    3,
+		Findings: []checker.Finding{
+			{Kind: checker.KindPriceChanged},
+			{Kind: checker.KindPriceChanged},
+		},
+	}
+	ObserveRun(report, nil)
+
+	if got := testutil.ToFloat64(RunsTotal.WithLabelValues("observed", "ok")); got != 1 {
+		t.Errorf("runs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(RowsChecked.WithLabelValues("observed")); got != 3 {
+		t.Errorf("rows checked = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(FindingsTotal.WithLabelValues("observed", string(checker.KindPriceChanged))); got != 2 {
+		t.Errorf("price findings = %v, want 2", got)
+	}
+	if got := sampleCount(t, RunDuration.WithLabelValues("observed")); got != 1 {
+		t.Errorf("timed runs = %v, want 1", got)
+	}
+}
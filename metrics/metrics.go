@@ -0,0 +1,25 @@
+// Package metrics keeps the counters and histograms of the checker and serves
+// them for Prometheus to scrape.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default holds the checker metrics, apart from the process wide registry
+// of the prometheus package.
+var Default = prometheus.NewRegistry()
+
+var factory = promauto.With(Default)
+
+// Handler serves the metrics of Default.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
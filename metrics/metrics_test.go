@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	RunsTotal.WithLabelValues("served", "ok").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(w.Body)
+	want := `dropship_check_runs_total{partner="served",result="ok"} 1`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Handler() body doesn't contain %q:\n%s", want, body)
+	}
+}
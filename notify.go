@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/notify"
+)
+
+// notifier returns the notifiers configured in the env, each behind its
+// minimum severity. Alerts go through their own alert state when there is
+// one; the email digest doesn't, since it sums up and attaches the whole run.
+// Notifiers that can't be set up are logged and left out. Rules route
+// findings to them by these names.
+func notifier() notify.Notifier {
+	res := notify.Routed{}
+	add := func(name string, n notify.Notifier, dedup bool) {
+		if dedup {
+			n = notify.NewDedup(name, n)
+		}
+		n, err := notify.NewMinSeverity(name, n)
+		if err != nil {
+			log.Println("[ERROR] [NewMinSeverity]", name, err)
+			return
+		}
+		res[name] = n
+	}
+
+	if w := notify.NewWebhook(); w != nil {
+		add("webhook", w, true)
+	}
+
+	if s, err := notify.NewSlack(); err != nil {
+		log.Println("[ERROR] [NewSlack]", err)
+	} else if s != nil {
+		add("slack", s, true)
+	}
+
+	if t, err := notify.NewTelegram(); err != nil {
+		log.Println("[ERROR] [NewTelegram]", err)
+	} else if t != nil {
+		add("telegram", t, true)
+	}
+
+	if e, err := notify.NewEmail(); err != nil {
+		log.Println("[ERROR] [NewEmail]", err)
+	} else if e != nil {
+		add("email", e, false)
+	}
+
+	return res
+}
+
+// opsAlerts returns the operational alert channel configured in the env, or
+// nil, which drops every alert.
+func opsAlerts() *notify.Ops {
+	o, err := notify.NewOps()
+	if err != nil {
+		log.Println("[ERROR] [NewOps]", err)
+	}
+	return o
+}
+
+// fatal sends a critical operational alert about err before logging it and
+// exiting.
+func fatal(ctx context.Context, op string, err error) {
+	alertFailure(ctx, op, err)
+	log.Fatalln("[ERROR] ["+op+"]", err)
+}
+
+// alertFailure sends a critical operational alert about op failing.
+func alertFailure(ctx context.Context, op string, err error) {
+	if alertErr := opsAlerts().Alert(ctx, notify.OpsAlert{
+		Severity: notify.SeverityCritical,
+		Partner:  os.Getenv(checker.PartnerNameEnvKey),
+		Title:    op + " failed",
+		Message:  err.Error(),
+	}); alertErr != nil {
+		log.Println("[ERROR] [sending ops alert]", alertErr)
+	}
+}
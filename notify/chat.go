@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	chatTemplateDirEnvKey = "CHAT_TEMPLATE_DIR"
+	productURLBaseEnvKey  = "PRODUCT_URL_BASE"
+)
+
+// maxGroupFindings is how many findings of a group a chat message lists.
+const maxGroupFindings = 20
+
+// maxFindingMessage is how many characters of a finding's message a chat
+// message shows. Partner errors carry part of the response body.
+const maxFindingMessage = 200
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// chatFinding is a finding with a link to the product.
+type chatFinding struct {
+	checker.Finding
+	URL string
+}
+
+type chatGroup struct {
+	Partner  string
+	Kind     checker.Kind
+	Total    int
+	Findings []chatFinding
+	More     int
+}
+
+type chatMessage struct {
+	Partner string
+	Total   int
+	Groups  []chatGroup
+}
+
+// newChatMessage groups the findings of report by partner and kind.
+func newChatMessage(report *checker.Report, productURLBase string) chatMessage {
+	type key struct {
+		partner string
+		kind    checker.Kind
+	}
+
+	groups := map[key]*chatGroup{}
+	var keys []key
+	for _, f := range report.Findings {
+		partner := f.Partner
+		if partner == "" {
+			partner = report.Partner
+		}
+
+		k := key{partner, f.Kind}
+		g, ok := groups[k]
+		if !ok {
+			g = &chatGroup{Partner: partner, Kind: f.Kind}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+
+		g.Total++
+		if len(g.Findings) == maxGroupFindings {
+			g.More++
+			continue
+		}
+
+		cf := chatFinding{Finding: f}
+		cf.Message = truncate(f.Message, maxFindingMessage)
+		if productURLBase != "" && f.Slug != "" {
+			cf.URL = productURLBase + url.PathEscape(f.Slug)
+		}
+		g.Findings = append(g.Findings, cf)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].partner != keys[j].partner {
+			return keys[i].partner < keys[j].partner
+		}
+		return keys[i].kind < keys[j].kind
+	})
+
+	m := chatMessage{Partner: report.Partner, Total: len(report.Findings)}
+	for _, k := range keys {
+		m.Groups = append(m.Groups, *groups[k])
+	}
+	return m
+}
+
+// loadChatTemplate parses <name>.tmpl from CHAT_TEMPLATE_DIR when it is
+// there, or the built-in one. It has to define "header" and "group".
+func loadChatTemplate(name string, funcs template.FuncMap) (*template.Template, error) {
+	file := name + ".tmpl"
+
+	var b []byte
+	var err error
+	if dir := os.Getenv(chatTemplateDirEnvKey); dir != "" {
+		b, err = ioutil.ReadFile(filepath.Join(dir, file))
+	}
+	if b == nil {
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		b, err = defaultTemplates.ReadFile("templates/" + file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return template.New(file).Funcs(funcs).Parse(string(b))
+}
+
+// truncate cuts s to at most n characters, marking the cut with "…".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// truncateLines cuts s to at most n bytes at the end of a line, so no markup
+// is left open, and marks the cut with "…".
+func truncateLines(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n-len("\n…")]
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s = s[:i]
+	}
+	return s + "\n…"
+}
+
+func execute(t *template.Template, name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+var chatReport = &checker.Report{
+	Partner: "acme",
+	Findings: []checker.Finding{
+		{Kind: checker.KindPriceChanged, Partner: "acme", SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: 1000, NewValue: 1200},
+		{Kind: checker.KindDiscontinued, Partner: "acme", SKU: "SKU-2", Slug: "old-hat"},
+		{Kind: checker.KindPriceChanged, Partner: "acme", SKU: "SKU-3", Slug: "blue&green", Variant: "M", OldValue: 500, NewValue: 400},
+	},
+}
+
+// stub records the JSON body of the last request it got.
+func stub(t *testing.T, body *map[string]interface{}, path *string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*path = r.URL.Path
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSlack_Notify(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	ts := stub(t, &body, &path)
+
+	os.Setenv(slackWebhookURLEnvKey, ts.URL+"/services/T000/B000/XXXX")
+	os.Setenv(productURLBaseEnvKey, "https://shop.example.com/p/")
+	defer os.Unsetenv(slackWebhookURLEnvKey)
+	defer os.Unsetenv(productURLBaseEnvKey)
+
+	s, err := NewSlack()
+	if err != nil {
+		t.Fatalf("NewSlack() error = %v", err)
+	}
+	if err := s.Notify(context.Background(), chatReport); err != nil {
+		t.Fatalf("Slack.Notify() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"text": "Dropship check for acme: 3 finding(s)",
+		"blocks": []interface{}{
+			map[string]interface{}{"type": "header", "text": map[string]interface{}{"type": "plain_text", "text": "Dropship check for acme: 3 finding(s)"}},
+			map[string]interface{}{"type": "divider"},
+			map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "*acme* · *price_changed* (2)\n" +
+				"• <https://shop.example.com/p/red-shirt|red-shirt> / L (SKU-1): 1000 → 1200\n" +
+				"• <https://shop.example.com/p/blue&green|blue&amp;green> / M (SKU-3): 500 → 400"}},
+			map[string]interface{}{"type": "divider"},
+			map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "*acme* · *product_discontinued* (1)\n" +
+				"• <https://shop.example.com/p/old-hat|old-hat> (SKU-2)"}},
+		},
+	}
+
+	if path != "/services/T000/B000/XXXX" {
+		t.Errorf("Slack.Notify() path = %v", path)
+	}
+	if !reflect.DeepEqual(body, want) {
+		got, _ := json.MarshalIndent(body, "", "  ")
+		t.Errorf("Slack.Notify() payload = %s", got)
+	}
+}
+
+func TestSlack_payload_longMessages(t *testing.T) {
+	s, err := newSlack(func(key string) string {
+		if key == slackWebhookURLEnvKey {
+			return "https://hooks.slack.example/services/T000"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("newSlack() error = %v", err)
+	}
+
+	report := &checker.Report{Partner: "acme"}
+	for i := 0; i < 30; i++ {
+		report.Findings = append(report.Findings, checker.Finding{
+			Kind:    checker.KindPartnerError,
+			Partner: "acme",
+			SKU:     fmt.Sprintf("SKU-%d", i),
+			Slug:    "red-shirt",
+			Message: "partner answered 502: " + strings.Repeat("<html>", 100),
+		})
+	}
+
+	p, err := s.payload(report)
+	if err != nil {
+		t.Fatalf("Slack.payload() error = %v", err)
+	}
+	for _, b := range p.Blocks {
+		if b.Type == "section" && utf8.RuneCountInString(b.Text.Text) >= 3000 {
+			t.Errorf("section text has %d characters, want less than 3000", utf8.RuneCountInString(b.Text.Text))
+		}
+	}
+}
+
+func TestTelegram_Notify(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	ts := stub(t, &body, &path)
+
+	os.Setenv(telegramBotTokenEnvKey, "123:abc")
+	os.Setenv(telegramChatIDEnvKey, "-100")
+	os.Setenv(telegramBaseURLEnvKey, ts.URL)
+	defer os.Unsetenv(telegramBotTokenEnvKey)
+	defer os.Unsetenv(telegramChatIDEnvKey)
+	defer os.Unsetenv(telegramBaseURLEnvKey)
+
+	tg, err := NewTelegram()
+	if err != nil {
+		t.Fatalf("NewTelegram() error = %v", err)
+	}
+	if err := tg.Notify(context.Background(), chatReport); err != nil {
+		t.Fatalf("Telegram.Notify() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"chat_id": "-100",
+		"text": "<b>Dropship check for acme</b>: 3 finding(s)\n\n" +
+			"<b>acme</b> · <b>price_changed</b> (2)\n" +
+			"• red-shirt / L (SKU-1): 1000 → 1200\n" +
+			"• blue&amp;green / M (SKU-3): 500 → 400\n\n" +
+			"<b>acme</b> · <b>product_discontinued</b> (1)\n" +
+			"• old-hat (SKU-2)",
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+
+	if path != "/bot123:abc/sendMessage" {
+		t.Errorf("Telegram.Notify() path = %v", path)
+	}
+	if !reflect.DeepEqual(body, want) {
+		got, _ := json.MarshalIndent(body, "", "  ")
+		t.Errorf("Telegram.Notify() payload = %s", got)
+	}
+}
+
+func TestLoadChatTemplate_override(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := `{{define "header"}}{{.Total}} new{{end}}{{define "group"}}{{.Kind}}{{end}}`
+	ioutil.WriteFile(filepath.Join(dir, "slack.tmpl"), []byte(tmpl), 0644)
+
+	os.Setenv(chatTemplateDirEnvKey, dir)
+	defer os.Unsetenv(chatTemplateDirEnvKey)
+
+	tm, err := loadChatTemplate("slack", slackFuncs)
+	if err != nil {
+		t.Fatalf("loadChatTemplate() error = %v", err)
+	}
+	if got, _ := execute(tm, "header", newChatMessage(chatReport, "")); got != "3 new" {
+		t.Errorf("header = %q, want %q", got, "3 new")
+	}
+
+	// telegram.tmpl isn't in dir, so the built-in one is used
+	if _, err := loadChatTemplate("telegram", telegramFuncs); err != nil {
+		t.Errorf("loadChatTemplate() error = %v", err)
+	}
+}
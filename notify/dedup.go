@@ -0,0 +1,219 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	alertStatePathEnvKey      = "ALERT_STATE_PATH"
+	alertRemindIntervalEnvKey = "ALERT_REMIND_INTERVAL"
+)
+
+const defaultAlertRemindInterval = 24 * time.Hour
+
+// resolvableKinds are the findings that go away once the CSV catches up
+// with the partner, and get a resolved notification when they do.
+var resolvableKinds = map[checker.Kind]bool{
+	checker.KindPriceChanged:      true,
+	checker.KindStockLevelChanged: true,
+}
+
+// Alert is a finding that was notified, kept across runs.
+type Alert struct {
+	Finding    checker.Finding `json:"finding"`
+	FirstSeen  time.Time       `json:"first_seen"`
+	NotifiedAt time.Time       `json:"notified_at"`
+}
+
+// alertState holds the alerts of every notifier by name.
+type alertState struct {
+	Version  int                          `json:"version"`
+	Backends map[string]map[string]*Alert `json:"backends"`
+}
+
+// stateMu serializes the Dedups sharing a state file.
+var stateMu sync.Mutex
+
+// Dedup keeps the alert state of past runs of one notifier in a file, so
+// next is only told about findings that are new, whose value changed again,
+// or that are due for a reminder, plus resolved findings. Runs with nothing
+// to tell are still passed on; notifiers that don't want them skip them.
+type Dedup struct {
+	next     Notifier
+	name     string
+	path     string
+	interval time.Duration
+}
+
+// NewDedup returns next as is when no alert state path is configured. The
+// alerts of next are kept under name. A zero ALERT_REMIND_INTERVAL turns
+// reminders off.
+func NewDedup(name string, next Notifier) Notifier {
+	path := os.Getenv(alertStatePathEnvKey)
+	if path == "" {
+		return next
+	}
+
+	interval := defaultAlertRemindInterval
+	if d, err := time.ParseDuration(os.Getenv(alertRemindIntervalEnvKey)); err == nil && d >= 0 {
+		interval = d
+	}
+
+	return &Dedup{next: next, name: name, path: path, interval: interval}
+}
+
+// Notify saves the new alert state only when next succeeds, so failed
+// notifications are tried again on the next run.
+func (d *Dedup) Notify(ctx context.Context, report *checker.Report) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	state, err := d.load()
+	if err != nil {
+		return err
+	}
+
+	alerts := state.Backends[d.name]
+	if alerts == nil {
+		alerts = map[string]*Alert{}
+	}
+
+	filtered := *report
+	filtered.Findings = d.update(alerts, report)
+	if err := d.next.Notify(ctx, &filtered); err != nil {
+		return err
+	}
+
+	state.Backends[d.name] = alerts
+	return d.save(state)
+}
+
+// update applies the findings of report to alerts and returns the ones to
+// notify.
+func (d *Dedup) update(alerts map[string]*Alert, report *checker.Report) []checker.Finding {
+	at := report.StartedAt
+	res := []checker.Finding{}
+
+	seen := map[string]bool{}
+	for _, f := range report.Findings {
+		if f.Partner == "" {
+			f.Partner = report.Partner
+		}
+		key := alertKey(f)
+		seen[key] = true
+
+		a, ok := alerts[key]
+		switch {
+		case !ok:
+			alerts[key] = &Alert{Finding: f, FirstSeen: at, NotifiedAt: at}
+		case a.Finding.NewValue != f.NewValue:
+			a.Finding = f
+			a.NotifiedAt = at
+		case d.interval > 0 && at.Sub(a.NotifiedAt) >= d.interval:
+			a.Finding = f
+			a.NotifiedAt = at
+		default:
+			a.Finding = f
+			continue
+		}
+		res = append(res, f)
+	}
+
+	checked := checkedKeys(report)
+	var keys []string
+	for key := range alerts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		a := alerts[key]
+		if seen[key] || !checked[checkedKey(a.Finding)] {
+			continue
+		}
+
+		delete(alerts, key)
+		if !resolvableKinds[a.Finding.Kind] {
+			continue
+		}
+
+		f := a.Finding
+		f.Kind = checker.KindResolved
+		f.Message = string(a.Finding.Kind) + " no longer reported"
+		res = append(res, f)
+	}
+
+	return res
+}
+
+func alertKey(f checker.Finding) string {
+	key := f.Partner + "|" + checkedKey(f) + "|" + string(f.Kind)
+	if f.Rule != "" {
+		key += "|" + f.Rule
+	}
+	return key
+}
+
+// checkedKey identifies the row of a finding by SKU, or by slug and variant
+// for findings without one.
+func checkedKey(f checker.Finding) string {
+	if f.SKU != "" {
+		return f.SKU
+	}
+	return f.Slug + "/" + f.Variant
+}
+
+// checkedKeys returns the rows the partner answered for in report. Alerts of
+// rows that were not checked, errored, or were quarantined as suspicious
+// stay as they are.
+func checkedKeys(report *checker.Report) map[string]bool {
+	quarantined := map[int]bool{}
+	for _, f := range report.Findings {
+		if f.Kind == checker.KindNeedsReview {
+			quarantined[f.Row] = true
+		}
+	}
+
+	res := map[string]bool{}
+	for _, o := range report.Observations {
+		if !quarantined[o.Row] {
+			res[checkedKey(checker.Finding{SKU: o.SKU, Slug: o.Slug, Variant: o.Variant.Name})] = true
+		}
+	}
+	return res
+}
+
+func (d *Dedup) load() (*alertState, error) {
+	state := &alertState{Version: 1, Backends: map[string]map[string]*Alert{}}
+
+	b, err := ioutil.ReadFile(d.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	if state.Backends == nil {
+		state.Backends = map[string]map[string]*Alert{}
+	}
+	return state, nil
+}
+
+func (d *Dedup) save(state *alertState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(d.path, b, 0644)
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+type recorder struct {
+	reports []*checker.Report
+	err     error
+}
+
+func (r *recorder) Notify(ctx context.Context, report *checker.Report) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestDedup_Notify(t *testing.T) {
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	observed := []checker.Observation{
+		{Row: 1, SKU: "SKU-1", Slug: "red-shirt", Variant: product.Variant{Name: "L"}},
+		{Row: 2, SKU: "SKU-2", Slug: "old-hat", Variant: product.Variant{Name: "default"}},
+	}
+	price := func(v int) checker.Finding {
+		return checker.Finding{Kind: checker.KindPriceChanged, SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: 1000, NewValue: v}
+	}
+
+	tests := []struct {
+		name         string
+		at           time.Duration
+		findings     []checker.Finding
+		observations []checker.Observation
+		err          error
+		want         []checker.Finding
+	}{
+		{
+			name:         "new finding",
+			findings:     []checker.Finding{price(1200)},
+			observations: observed,
+			want:         []checker.Finding{price(1200)},
+		},
+		{
+			name:         "same finding",
+			at:           time.Hour,
+			findings:     []checker.Finding{price(1200)},
+			observations: observed,
+		},
+		{
+			name:         "failing notifier",
+			at:           2 * time.Hour,
+			findings:     []checker.Finding{price(1300)},
+			observations: observed,
+			err:          errors.New("sample error"),
+		},
+		{
+			name:         "changed value",
+			at:           3 * time.Hour,
+			findings:     []checker.Finding{price(1300)},
+			observations: observed,
+			want:         []checker.Finding{price(1300)},
+		},
+		{
+			name:         "row not checked",
+			at:           4 * time.Hour,
+			observations: observed[1:],
+		},
+		{
+			name:         "reminder",
+			at:           27 * time.Hour,
+			findings:     []checker.Finding{price(1300)},
+			observations: observed,
+			want:         []checker.Finding{price(1300)},
+		},
+		{
+			name:         "resolved",
+			at:           28 * time.Hour,
+			observations: observed,
+			want: []checker.Finding{{
+				Kind:     checker.KindResolved,
+				SKU:      "SKU-1",
+				Slug:     "red-shirt",
+				Variant:  "L",
+				OldValue: 1000,
+				NewValue: 1300,
+				Message:  "price_changed no longer reported",
+			}},
+		},
+	}
+
+	next := &recorder{}
+	d := &Dedup{next: next, name: "sample", path: filepath.Join(t.TempDir(), "alerts.json"), interval: defaultAlertRemindInterval}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next.reports, next.err = nil, tt.err
+
+			report := &checker.Report{
+				StartedAt:    start.Add(tt.at),
+				Findings:     tt.findings,
+				Observations: tt.observations,
+			}
+			err := d.Notify(context.Background(), report)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Dedup.Notify() error = %v, want %v", err, tt.err)
+			}
+
+			var got []checker.Finding
+			for _, r := range next.reports {
+				got = append(got, r.Findings...)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("notified findings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedup_Notify_emptyRun(t *testing.T) {
+	next := &recorder{}
+	d := &Dedup{next: next, name: "email", path: filepath.Join(t.TempDir(), "alerts.json")}
+
+	if err := d.Notify(context.Background(), &checker.Report{}); err != nil {
+		t.Fatalf("Dedup.Notify() error = %v", err)
+	}
+	if len(next.reports) != 1 {
+		t.Errorf("reports passed on = %v, want 1", len(next.reports))
+	}
+}
+
+func TestDedup_Notify_quarantined(t *testing.T) {
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	observed := []checker.Observation{{Row: 1, SKU: "SKU-1", Variant: product.Variant{Price: 990000}}}
+	price := checker.Finding{Kind: checker.KindPriceChanged, Row: 1, SKU: "SKU-1", OldValue: 1000, NewValue: 1200}
+	review := checker.Finding{Kind: checker.KindNeedsReview, Row: 1, SKU: "SKU-1", NewValue: 990000}
+
+	next := &recorder{}
+	d := &Dedup{next: next, name: "sample", path: filepath.Join(t.TempDir(), "alerts.json")}
+	d.Notify(context.Background(), &checker.Report{StartedAt: start, Findings: []checker.Finding{price}, Observations: observed})
+
+	next.reports = nil
+	report := &checker.Report{StartedAt: start.Add(time.Hour), Findings: []checker.Finding{review}, Observations: observed}
+	if err := d.Notify(context.Background(), report); err != nil {
+		t.Fatalf("Dedup.Notify() error = %v", err)
+	}
+	for _, f := range next.reports[0].Findings {
+		if f.Kind == checker.KindResolved {
+			t.Errorf("quarantined reading notified as resolved: %+v", f)
+		}
+	}
+}
+
+func TestDedup_Notify_perNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	healthy, failing := &recorder{}, &recorder{err: errors.New("sample error")}
+	r := Routed{
+		"healthy": &Dedup{next: healthy, name: "healthy", path: path},
+		"failing": &Dedup{next: failing, name: "failing", path: path},
+	}
+
+	report := &checker.Report{Findings: []checker.Finding{{Kind: checker.KindDiscontinued, SKU: "SKU-1"}}}
+	for i := 0; i < 2; i++ {
+		if err := r.Notify(context.Background(), report); err == nil {
+			t.Fatalf("Routed.Notify() error = nil, want error")
+		}
+	}
+
+	if got := len(healthy.reports[0].Findings) + len(healthy.reports[1].Findings); got != 1 {
+		t.Errorf("findings sent to the healthy notifier = %v, want 1", got)
+	}
+}
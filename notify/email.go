@@ -0,0 +1,353 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+const (
+	smtpHostEnvKey     = "SMTP_HOST"
+	smtpPortEnvKey     = "SMTP_PORT"
+	smtpUsernameEnvKey = "SMTP_USERNAME"
+	smtpPasswordEnvKey = "SMTP_PASSWORD"
+	smtpFromEnvKey     = "SMTP_FROM"
+	smtpToEnvKey       = "SMTP_TO"
+	smtpStartTLSEnvKey = "SMTP_STARTTLS"
+)
+
+const (
+	defaultSMTPPort = 587
+	smtpTimeout     = 30 * time.Second
+)
+
+// Email sends a digest of every check run over SMTP, with the JSON report
+// attached. The connection is upgraded with STARTTLS unless SMTP_STARTTLS is
+// false, which is only meant for local relays.
+type Email struct {
+	addr      string
+	host      string
+	username  string
+	password  string
+	from      string
+	to        []string
+	startTLS  bool
+	tlsConfig *tls.Config
+	html      *htmltemplate.Template
+	text      *template.Template
+}
+
+type emailPriceChange struct {
+	checker.Finding
+	Percent float64
+}
+
+type emailKindCount struct {
+	Kind  checker.Kind
+	Count int
+}
+
+type emailDigest struct {
+	Partner      string
+	StartedAt    time.Time
+	Checked      int
+	Unchecked    int
+	Total        int
+	Counts       []emailKindCount
+	PriceChanges []emailPriceChange
+	OutOfStock   []checker.Finding
+}
+
+// NewEmail returns nil when no SMTP host is configured.
+func NewEmail() (*Email, error) {
+	return newEmail(os.Getenv)
+}
+
+func newEmail(getenv func(string) string) (*Email, error) {
+	host := getenv(smtpHostEnvKey)
+	if host == "" {
+		return nil, nil
+	}
+
+	to := splitList(getenv(smtpToEnvKey))
+	if len(to) == 0 {
+		return nil, errors.New("no email recipients: " + smtpToEnvKey + " is empty")
+	}
+
+	from := getenv(smtpFromEnvKey)
+	if from == "" {
+		from = getenv(smtpUsernameEnvKey)
+	}
+
+	startTLS := true
+	if s := getenv(smtpStartTLSEnvKey); s != "" {
+		var err error
+		if startTLS, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", smtpStartTLSEnvKey, err)
+		}
+	}
+
+	html, err := htmltemplate.ParseFS(defaultTemplates, "templates/email.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	text, err := template.ParseFS(defaultTemplates, "templates/email.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		addr:      net.JoinHostPort(host, strconv.Itoa(atoi(getenv(smtpPortEnvKey), defaultSMTPPort))),
+		host:      host,
+		username:  getenv(smtpUsernameEnvKey),
+		password:  getenv(smtpPasswordEnvKey),
+		from:      from,
+		to:        to,
+		startTLS:  startTLS,
+		tlsConfig: &tls.Config{ServerName: host},
+		html:      html,
+		text:      text,
+	}, nil
+}
+
+// Notify sends the digest even when the run has no findings, so a silent
+// inbox never hides a checker that stopped running.
+func (e *Email) Notify(ctx context.Context, report *checker.Report) error {
+	msg, err := e.message(report)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+// NotifyOps sends the operational alert a as a plain text email.
+func (e *Email) NotifyOps(ctx context.Context, a OpsAlert) error {
+	var msg bytes.Buffer
+	e.writeHeader(&msg, a.Summary())
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qw := quotedprintable.NewWriter(&msg)
+	fmt.Fprintf(qw, "%s\n\n%s\n\nAt: %s\n", a.Summary(), a.Message, a.Time.Format(time.RFC1123Z))
+	if err := qw.Close(); err != nil {
+		return err
+	}
+
+	return e.send(ctx, msg.Bytes())
+}
+
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	d := net.Dialer{Timeout: smtpTimeout}
+	conn, err := d.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.startTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(e.tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// message builds a multipart/mixed message holding the text and HTML digest
+// as alternatives and the JSON report as an attachment.
+func (e *Email) message(report *checker.Report) ([]byte, error) {
+	d := newEmailDigest(report)
+
+	var text, html bytes.Buffer
+	if err := e.text.Execute(&text, d); err != nil {
+		return nil, err
+	}
+	if err := e.html.Execute(&html, d); err != nil {
+		return nil, err
+	}
+
+	attachment, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var alt bytes.Buffer
+	aw := multipart.NewWriter(&alt)
+	if err := writeQuotedPrintable(aw, "text/plain; charset=utf-8", text.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(aw, "text/html; charset=utf-8", html.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + aw.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(alt.Bytes())
+
+	filename := "report-" + report.StartedAt.Format("20060102-150405") + ".json"
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, attachment)
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	e.writeHeader(&msg, emailSubject(d))
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func (e *Email) writeHeader(w io.Writer, subject string) {
+	fmt.Fprintf(w, "From: %s\r\n", e.from)
+	fmt.Fprintf(w, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(w, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(w, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(w, "MIME-Version: 1.0\r\n")
+}
+
+func newEmailDigest(report *checker.Report) emailDigest {
+	d := emailDigest{
+		Partner:   report.Partner,
+		StartedAt: report.StartedAt,
+		Checked:   report.Checked,
+		Unchecked: len(report.Unchecked),
+		Total:     len(report.Findings),
+	}
+
+	for kind, n := range report.CountByKind() {
+		d.Counts = append(d.Counts, emailKindCount{Kind: kind, Count: n})
+	}
+	sort.Slice(d.Counts, func(i, j int) bool { return d.Counts[i].Kind < d.Counts[j].Kind })
+
+	for _, f := range report.Findings {
+		switch {
+		case f.Kind == checker.KindPriceChanged:
+			pc := emailPriceChange{Finding: f}
+			if f.OldValue != 0 {
+				pc.Percent = float64(f.NewValue-f.OldValue) / float64(f.OldValue) * 100
+			}
+			d.PriceChanges = append(d.PriceChanges, pc)
+		case f.Kind == checker.KindStockLevelChanged && f.NewValue == product.OutOfStock:
+			d.OutOfStock = append(d.OutOfStock, f)
+		}
+	}
+
+	return d
+}
+
+func emailSubject(d emailDigest) string {
+	s := "Dropship check"
+	if d.Partner != "" {
+		s += " for " + d.Partner
+	}
+	return fmt.Sprintf("%s: %d findings (%s)", s, d.Total, d.StartedAt.Format("2006-01-02"))
+}
+
+func writeQuotedPrintable(w *multipart.Writer, contentType string, b []byte) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qw := quotedprintable.NewWriter(part)
+	if _, err := qw.Write(b); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// writeBase64 writes b base64 encoded in lines of 76 characters, as MIME
+// asks for.
+func writeBase64(w io.Writer, b []byte) {
+	s := base64.StdEncoding.EncodeToString(b)
+	for len(s) > 76 {
+		fmt.Fprintf(w, "%s\r\n", s[:76])
+		s = s[76:]
+	}
+	fmt.Fprintf(w, "%s\r\n", s)
+}
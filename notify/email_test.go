@@ -0,0 +1,291 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// smtpServer is a local SMTP stand-in that speaks just enough of the
+// protocol for net/smtp: EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT and DATA.
+type smtpServer struct {
+	ln  net.Listener
+	tls *tls.Config
+
+	mu   sync.Mutex
+	auth string
+	from string
+	to   []string
+	data string
+	tlsd bool
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Borrow the certificate of a TLS test server, it is valid for 127.0.0.1.
+	ts := httptest.NewTLSServer(nil)
+	cfg := ts.TLS.Clone()
+	ts.Close()
+
+	s := &smtpServer{ln: ln, tls: cfg}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(strings.TrimPrefix(line, strings.SplitN(line, " ", 2)[0]))
+
+		switch cmd {
+		case "EHLO":
+			s.mu.Lock()
+			tlsd := s.tlsd
+			s.mu.Unlock()
+			if tlsd {
+				tc.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			} else {
+				tc.PrintfLine("250-localhost\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			tc.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tc = textproto.NewConn(conn)
+			s.mu.Lock()
+			s.tlsd = true
+			s.mu.Unlock()
+		case "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.mu.Lock()
+			s.auth = string(b)
+			s.mu.Unlock()
+			tc.PrintfLine("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = arg
+			s.mu.Unlock()
+			tc.PrintfLine("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.to = append(s.to, arg)
+			s.mu.Unlock()
+			tc.PrintfLine("250 ok")
+		case "DATA":
+			tc.PrintfLine("354 go ahead")
+			b, err := ioutil.ReadAll(tc.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = string(b)
+			s.mu.Unlock()
+			tc.PrintfLine("250 ok")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	s := newSMTPServer(t)
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+
+	env := map[string]string{
+		smtpHostEnvKey:     host,
+		smtpPortEnvKey:     port,
+		smtpUsernameEnvKey: "checker",
+		smtpPasswordEnvKey: "secret",
+		smtpFromEnvKey:     "checker@example.com",
+		smtpToEnvKey:       "ops@example.com, buyer@example.com",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	e, err := NewEmail()
+	if err != nil {
+		t.Fatalf("NewEmail() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	cert, _ := x509.ParseCertificate(s.tls.Certificates[0].Certificate[0])
+	roots.AddCert(cert)
+	e.tlsConfig.RootCAs = roots
+
+	report := &checker.Report{
+		Partner:   "acme",
+		StartedAt: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC),
+		Checked:   3,
+		Findings: []checker.Finding{
+			{Kind: checker.KindPriceChanged, SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: 1000, NewValue: 1200},
+			{Kind: checker.KindStockLevelChanged, SKU: "SKU-2", Slug: "old-hat", OldValue: product.LowStock, NewValue: product.OutOfStock},
+			{Kind: checker.KindStockLevelChanged, SKU: "SKU-3", Slug: "<b>cap</b>", OldValue: product.OutOfStock, NewValue: product.HighStock},
+		},
+	}
+
+	if err := e.Notify(context.Background(), report); err != nil {
+		t.Fatalf("Email.Notify() error = %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tlsd {
+		t.Errorf("connection was not upgraded with STARTTLS")
+	}
+	if s.auth != "\x00checker\x00secret" {
+		t.Errorf("AUTH PLAIN = %q", s.auth)
+	}
+	if s.from != "FROM:<checker@example.com>" {
+		t.Errorf("MAIL = %q", s.from)
+	}
+	if len(s.to) != 2 {
+		t.Errorf("RCPT = %q, want 2 recipients", s.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(s.data))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage() error = %v", err)
+	}
+	if got, want := msg.Header.Get("Subject"), "Dropship check for acme: 3 findings (2022-05-01)"; got != want {
+		t.Errorf("Subject = %q, want %q", got, want)
+	}
+
+	parts := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want text, html and attachment", len(parts))
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{
+		"price_changed: 1",
+		"stock_level_changed: 2",
+		"SKU-1 red-shirt / L: 1000 -> 1200 (+20.0%)",
+		"Newly out of stock:\n  SKU-2 old-hat",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "SKU-3") {
+		t.Errorf("text part lists a SKU that is back in stock:\n%s", text)
+	}
+
+	if html := parts["text/html"]; strings.Contains(html, "<b>cap") {
+		t.Errorf("html part does not escape slugs:\n%s", html)
+	}
+
+	var got checker.Report
+	if err := json.Unmarshal([]byte(parts["application/json"]), &got); err != nil {
+		t.Fatalf("attachment is not the JSON report: %v", err)
+	}
+	if len(got.Findings) != len(report.Findings) {
+		t.Errorf("attached report has %d findings, want %d", len(got.Findings), len(report.Findings))
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	e, err := NewEmail()
+	if e != nil || err != nil {
+		t.Errorf("NewEmail() without SMTP_HOST = %v, %v, want nil, nil", e, err)
+	}
+
+	os.Setenv(smtpHostEnvKey, "smtp.example.com")
+	defer os.Unsetenv(smtpHostEnvKey)
+	if _, err := NewEmail(); err == nil {
+		t.Errorf("NewEmail() without recipients error = nil")
+	}
+}
+
+// readParts returns the decoded leaf parts of a multipart body by media type.
+func readParts(t *testing.T, contentType string, body io.Reader) map[string]string {
+	res := map[string]string{}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("mime.ParseMediaType(%q) error = %v", contentType, err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		t.Fatalf("media type = %q, want multipart", mediaType)
+	}
+
+	r := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+
+		ct := p.Header.Get("Content-Type")
+		if strings.HasPrefix(ct, "multipart/") {
+			for k, v := range readParts(t, ct, p) {
+				res[k] = v
+			}
+			continue
+		}
+
+		var b []byte
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			b, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		} else {
+			// The multipart reader decodes quoted-printable on its own.
+			b, err = ioutil.ReadAll(p)
+		}
+		if err != nil {
+			t.Fatalf("reading %s part: %v", ct, err)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		res[mediaType] = strings.ReplaceAll(string(b), "\r\n", "\n")
+	}
+
+	return res
+}
@@ -0,0 +1,55 @@
+// Package notify sends the findings of check runs to people and systems.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/andrysds/dropship-checker/backoff"
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+// Notifier sends the findings of a check run somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, report *checker.Report) error
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// postJSON POSTs payload as JSON to url and fails on non 2xx responses.
+func postJSON(ctx context.Context, c httpClient, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("got this status code: %d, body: %s", res.StatusCode, b)
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+// sleep is a variable so tests don't have to wait for real retry delays.
+var sleep = backoff.Sleep
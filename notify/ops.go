@@ -0,0 +1,292 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	opsEnvPrefix                = "OPS_"
+	opsMinSeverityEnvKey        = "OPS_MIN_SEVERITY"
+	opsErrorRateThresholdEnvKey = "OPS_ERROR_RATE_THRESHOLD"
+	opsUnreachableRunsEnvKey    = "OPS_UNREACHABLE_RUNS"
+	opsStatePathEnvKey          = "OPS_STATE_PATH"
+)
+
+const (
+	defaultOpsErrorRateThreshold = 0.5
+	defaultOpsUnreachableRuns    = 3
+)
+
+// Severity tells how urgent an operational alert is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "warning", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return strconv.Itoa(int(s))
+	}
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity is the inverse of Severity.String.
+func ParseSeverity(s string) (Severity, error) {
+	for i, name := range severityNames {
+		if strings.EqualFold(s, name) {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity: %s", s)
+}
+
+// OpsAlert is about the checker itself failing, rather than about the
+// products it checks.
+type OpsAlert struct {
+	Type     string    `json:"type"`
+	Severity Severity  `json:"severity"`
+	Partner  string    `json:"partner,omitempty"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Summary is a one line description of the alert.
+func (a OpsAlert) Summary() string {
+	s := "[" + strings.ToUpper(a.Severity.String()) + "] "
+	if a.Partner != "" {
+		s += a.Partner + ": "
+	}
+	return s + a.Title
+}
+
+// OpsNotifier sends operational alerts somewhere.
+type OpsNotifier interface {
+	NotifyOps(ctx context.Context, a OpsAlert) error
+}
+
+// failedKinds are the findings of rows the partner didn't answer for.
+var failedKinds = map[checker.Kind]bool{
+	checker.KindPartnerError:       true,
+	checker.KindPartnerUnavailable: true,
+	checker.KindUnauthorized:       true,
+	checker.KindRateLimited:        true,
+	checker.KindMalformedResponse:  true,
+}
+
+// Ops is the operational alert channel. It uses the same backends as the
+// findings, configured with the OPS_ prefixed env keys when they are set, so
+// e.g. OPS_SLACK_WEBHOOK_URL sends them to another Slack channel and an empty
+// one keeps them out of Slack.
+type Ops struct {
+	notifiers       []OpsNotifier
+	minSeverity     Severity
+	errorRate       float64
+	unreachableRuns int
+	statePath       string
+}
+
+// NewOps returns nil when no backend is configured. Alerts sent to a nil
+// *Ops are dropped.
+func NewOps() (*Ops, error) {
+	getenv := func(key string) string {
+		if v, ok := os.LookupEnv(opsEnvPrefix + key); ok {
+			return v
+		}
+		return os.Getenv(key)
+	}
+
+	var notifiers []OpsNotifier
+	if w := newWebhook(getenv); w != nil {
+		notifiers = append(notifiers, w)
+	}
+	s, err := newSlack(getenv)
+	if err != nil {
+		return nil, err
+	} else if s != nil {
+		notifiers = append(notifiers, s)
+	}
+	t, err := newTelegram(getenv)
+	if err != nil {
+		return nil, err
+	} else if t != nil {
+		notifiers = append(notifiers, t)
+	}
+	e, err := newEmail(getenv)
+	if err != nil {
+		return nil, err
+	} else if e != nil {
+		notifiers = append(notifiers, e)
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+
+	o := &Ops{
+		notifiers:       notifiers,
+		minSeverity:     SeverityInfo,
+		errorRate:       defaultOpsErrorRateThreshold,
+		unreachableRuns: atoi(os.Getenv(opsUnreachableRunsEnvKey), defaultOpsUnreachableRuns),
+		statePath:       os.Getenv(opsStatePathEnvKey),
+	}
+	if s := os.Getenv(opsMinSeverityEnvKey); s != "" {
+		if o.minSeverity, err = ParseSeverity(s); err != nil {
+			return nil, err
+		}
+	}
+	if v, err := strconv.ParseFloat(os.Getenv(opsErrorRateThresholdEnvKey), 64); err == nil && v > 0 {
+		o.errorRate = v
+	}
+
+	return o, nil
+}
+
+// Alert sends a to every backend, unless it is below the minimum severity.
+func (o *Ops) Alert(ctx context.Context, a OpsAlert) error {
+	if o == nil || a.Severity < o.minSeverity {
+		return nil
+	}
+
+	a.Type = "ops_alert"
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+
+	var msgs []string
+	for _, n := range o.notifiers {
+		if err := n.NotifyOps(ctx, a); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// CheckRun alerts when the run failed, when too many of its rows errored, or
+// when the partner has been unreachable for OPS_UNREACHABLE_RUNS runs in a
+// row. Counting runs needs OPS_STATE_PATH.
+func (o *Ops) CheckRun(ctx context.Context, report *checker.Report, runErr error) error {
+	if o == nil {
+		return nil
+	}
+
+	var alerts []OpsAlert
+
+	if runErr != nil && !errors.Is(runErr, context.Canceled) {
+		alerts = append(alerts, OpsAlert{
+			Severity: SeverityCritical,
+			Partner:  report.Partner,
+			Title:    "check run failed",
+			Message:  runErr.Error(),
+		})
+	}
+
+	failed := 0
+	for _, f := range report.Findings {
+		if failedKinds[f.Kind] {
+			failed++
+		}
+	}
+	if report.Checked > 0 && float64(failed)/float64(report.Checked) >= o.errorRate {
+		alerts = append(alerts, OpsAlert{
+			Severity: SeverityWarning,
+			Partner:  report.Partner,
+			Title:    "error rate above threshold",
+			Message:  fmt.Sprintf("%d of %d checked rows failed", failed, report.Checked),
+		})
+	}
+
+	unreachable := runErr != nil && report.Checked == 0 && !errors.Is(runErr, context.Canceled) ||
+		report.Checked > 0 && failed == report.Checked
+	if a, err := o.countUnreachable(report.Partner, unreachable); err != nil {
+		return err
+	} else if a != nil {
+		alerts = append(alerts, *a)
+	}
+
+	var msgs []string
+	for _, a := range alerts {
+		a.Time = report.StartedAt
+		if err := o.Alert(ctx, a); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// countUnreachable keeps the number of unreachable runs in a row per partner
+// and returns an alert when it reaches the limit, or when the partner is
+// back after reaching it.
+func (o *Ops) countUnreachable(partner string, unreachable bool) (*OpsAlert, error) {
+	if o.statePath == "" {
+		return nil, nil
+	}
+
+	runs := map[string]int{}
+	b, err := ioutil.ReadFile(o.statePath)
+	if err == nil {
+		err = json.Unmarshal(b, &runs)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	prev := runs[partner]
+	if unreachable {
+		runs[partner]++
+	} else {
+		delete(runs, partner)
+	}
+
+	if b, err = json.MarshalIndent(runs, "", "  "); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(o.statePath, b, 0644); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case unreachable && runs[partner] == o.unreachableRuns:
+		return &OpsAlert{
+			Severity: SeverityCritical,
+			Partner:  partner,
+			Title:    "partner unreachable",
+			Message:  fmt.Sprintf("no answer from the partner for %d runs in a row", runs[partner]),
+		}, nil
+	case !unreachable && prev >= o.unreachableRuns:
+		return &OpsAlert{
+			Severity: SeverityInfo,
+			Partner:  partner,
+			Title:    "partner reachable again",
+			Message:  fmt.Sprintf("after %d unreachable runs", prev),
+		}, nil
+	}
+	return nil, nil
+}
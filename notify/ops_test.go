@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+type opsRecorder struct {
+	alerts []OpsAlert
+}
+
+func (r *opsRecorder) NotifyOps(ctx context.Context, a OpsAlert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestOps_CheckRun(t *testing.T) {
+	failed := checker.Finding{Kind: checker.KindPartnerUnavailable}
+	changed := checker.Finding{Kind: checker.KindPriceChanged}
+
+	tests := []struct {
+		name     string
+		checked  int
+		findings []checker.Finding
+		err      error
+		want     []string
+	}{
+		{
+			name:     "error rate",
+			checked:  4,
+			findings: []checker.Finding{failed, failed, changed},
+			want:     []string{"[WARNING] acme: error rate above threshold"},
+		},
+		{
+			name: "login failed",
+			err:  errors.New("got this status code: 401"),
+			want: []string{"[CRITICAL] acme: check run failed"},
+		},
+		{
+			name:     "every row failed",
+			checked:  2,
+			findings: []checker.Finding{failed, failed},
+			want:     []string{"[WARNING] acme: error rate above threshold", "[CRITICAL] acme: partner unreachable"},
+		},
+		{
+			name:     "still unreachable",
+			checked:  2,
+			findings: []checker.Finding{failed, failed},
+			want:     []string{"[WARNING] acme: error rate above threshold"},
+		},
+		{
+			name:     "recovered",
+			checked:  2,
+			findings: []checker.Finding{changed},
+			want:     []string{"[INFO] acme: partner reachable again"},
+		},
+		{
+			name: "canceled",
+			err:  context.Canceled,
+		},
+	}
+
+	r := &opsRecorder{}
+	o := &Ops{
+		notifiers:       []OpsNotifier{r},
+		minSeverity:     SeverityInfo,
+		errorRate:       0.5,
+		unreachableRuns: 2,
+		statePath:       filepath.Join(t.TempDir(), "ops.json"),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.alerts = nil
+			report := &checker.Report{
+				Partner:   "acme",
+				StartedAt: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC),
+				Checked:   tt.checked,
+				Findings:  tt.findings,
+			}
+
+			if err := o.CheckRun(context.Background(), report, tt.err); err != nil {
+				t.Fatalf("Ops.CheckRun() error = %v", err)
+			}
+
+			var got []string
+			for _, a := range r.alerts {
+				got = append(got, a.Summary())
+				if !a.Time.Equal(report.StartedAt) {
+					t.Errorf("alert time = %v, want %v", a.Time, report.StartedAt)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alerts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOps_Alert(t *testing.T) {
+	r := &opsRecorder{}
+	o := &Ops{notifiers: []OpsNotifier{r}, minSeverity: SeverityWarning}
+
+	o.Alert(context.Background(), OpsAlert{Severity: SeverityInfo, Title: "sample info"})
+	o.Alert(context.Background(), OpsAlert{Severity: SeverityCritical, Title: "sample critical"})
+
+	if len(r.alerts) != 1 || r.alerts[0].Title != "sample critical" {
+		t.Errorf("alerts = %+v, want only the critical one", r.alerts)
+	}
+
+	var nilOps *Ops
+	if err := nilOps.Alert(context.Background(), OpsAlert{Severity: SeverityCritical}); err != nil {
+		t.Errorf("nil Ops.Alert() error = %v", err)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityCritical} {
+		got, err := ParseSeverity(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseSeverity(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+	if _, err := ParseSeverity("sample"); err == nil {
+		t.Errorf("ParseSeverity(%q) error = nil", "sample")
+	}
+}
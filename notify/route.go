@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+// Routed notifies every notifier by name of the findings routed to it.
+// Findings without routes go to all of them.
+type Routed map[string]Notifier
+
+func (r Routed) Notify(ctx context.Context, report *checker.Report) error {
+	var names []string
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var msgs []string
+	for _, name := range names {
+		routed := *report
+		routed.Findings = []checker.Finding{}
+		for _, f := range report.Findings {
+			if len(f.Routes) == 0 || contains(f.Routes, name) {
+				routed.Findings = append(routed.Findings, f)
+			}
+		}
+
+		if err := r[name].Notify(ctx, &routed); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+const minSeverityEnvKeySuffix = "_MIN_SEVERITY"
+
+// MinSeverity passes on the findings of at least min severity. Findings
+// rules gave no severity count as info.
+type MinSeverity struct {
+	next Notifier
+	min  Severity
+}
+
+// NewMinSeverity reads the minimum severity of the notifier name from
+// <NAME>_MIN_SEVERITY, like SLACK_MIN_SEVERITY. It returns next as is when
+// that is unset or info.
+func NewMinSeverity(name string, next Notifier) (Notifier, error) {
+	s := os.Getenv(strings.ToUpper(name) + minSeverityEnvKeySuffix)
+	if s == "" {
+		return next, nil
+	}
+
+	min, err := ParseSeverity(s)
+	if err != nil {
+		return nil, err
+	}
+	if min == SeverityInfo {
+		return next, nil
+	}
+	return &MinSeverity{next: next, min: min}, nil
+}
+
+func (m *MinSeverity) Notify(ctx context.Context, report *checker.Report) error {
+	filtered := *report
+	filtered.Findings = []checker.Finding{}
+	for _, f := range report.Findings {
+		if severity, _ := ParseSeverity(f.Severity); severity >= m.min {
+			filtered.Findings = append(filtered.Findings, f)
+		}
+	}
+	return m.next.Notify(ctx, &filtered)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+func TestRouted_Notify(t *testing.T) {
+	slack, email := &recorder{}, &recorder{}
+	r := Routed{"slack": slack, "email": email}
+
+	report := &checker.Report{Findings: []checker.Finding{
+		{Kind: checker.KindPriceChanged, SKU: "SKU-1"},
+		{Kind: checker.KindRuleMatched, SKU: "SKU-2", Routes: []string{"slack"}},
+	}}
+	if err := r.Notify(context.Background(), report); err != nil {
+		t.Fatalf("Routed.Notify() error = %v", err)
+	}
+
+	if got := len(slack.reports[0].Findings); got != 2 {
+		t.Errorf("slack findings = %d, want 2", got)
+	}
+	if got := len(email.reports[0].Findings); got != 1 {
+		t.Errorf("email findings = %d, want 1", got)
+	}
+	if len(report.Findings) != 2 {
+		t.Errorf("Routed.Notify() changed the report")
+	}
+}
+
+func TestMinSeverity_Notify(t *testing.T) {
+	next := &recorder{}
+	m := &MinSeverity{next: next, min: SeverityWarning}
+
+	report := &checker.Report{Findings: []checker.Finding{
+		{Kind: checker.KindPriceChanged, SKU: "SKU-1"},
+		{Kind: checker.KindPriceChanged, SKU: "SKU-2", Severity: "warning"},
+		{Kind: checker.KindRuleMatched, SKU: "SKU-3", Severity: "critical"},
+	}}
+	if err := m.Notify(context.Background(), report); err != nil {
+		t.Fatalf("MinSeverity.Notify() error = %v", err)
+	}
+
+	if got := len(next.reports[0].Findings); got != 2 {
+		t.Errorf("findings passed on = %d, want 2", got)
+	}
+}
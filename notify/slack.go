@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	slackWebhookURLEnvKey = "SLACK_WEBHOOK_URL"
+	// slackMaxSectionLength keeps section texts below Slack's limit of 3000
+	// characters, which fails the whole message.
+	slackMaxSectionLength = 2900
+)
+
+// Slack posts findings as Block Kit messages to a Slack incoming webhook.
+type Slack struct {
+	httpClient     httpClient
+	webhookURL     string
+	productURLBase string
+	template       *template.Template
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var slackFuncs = template.FuncMap{
+	"esc": slackEscaper.Replace,
+	"link": func(url, text string) string {
+		return "<" + url + "|" + slackEscaper.Replace(text) + ">"
+	},
+}
+
+// NewSlack returns nil when no Slack webhook URL is configured.
+func NewSlack() (*Slack, error) {
+	return newSlack(os.Getenv)
+}
+
+func newSlack(getenv func(string) string) (*Slack, error) {
+	webhookURL := getenv(slackWebhookURLEnvKey)
+	if webhookURL == "" {
+		return nil, nil
+	}
+
+	t, err := loadChatTemplate("slack", slackFuncs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slack{
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		webhookURL:     webhookURL,
+		productURLBase: getenv(productURLBaseEnvKey),
+		template:       t,
+	}, nil
+}
+
+func (s *Slack) Notify(ctx context.Context, report *checker.Report) error {
+	if len(report.Findings) == 0 {
+		return nil
+	}
+
+	payload, err := s.payload(report)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.httpClient, s.webhookURL, payload)
+}
+
+// NotifyOps posts the operational alert a as a plain message.
+func (s *Slack) NotifyOps(ctx context.Context, a OpsAlert) error {
+	text := "*" + slackEscaper.Replace(a.Summary()) + "*"
+	if a.Message != "" {
+		text += "\n" + slackEscaper.Replace(a.Message)
+	}
+
+	return postJSON(ctx, s.httpClient, s.webhookURL, &slackPayload{
+		Text:   a.Summary(),
+		Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncateLines(text, slackMaxSectionLength)}}},
+	})
+}
+
+func (s *Slack) payload(report *checker.Report) (*slackPayload, error) {
+	m := newChatMessage(report, s.productURLBase)
+
+	header, err := execute(s.template, "header", m)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &slackPayload{
+		Text:   header,
+		Blocks: []slackBlock{{Type: "header", Text: &slackText{Type: "plain_text", Text: header}}},
+	}
+	for _, g := range m.Groups {
+		text, err := execute(s.template, "group", g)
+		if err != nil {
+			return nil, err
+		}
+		p.Blocks = append(p.Blocks,
+			slackBlock{Type: "divider"},
+			slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncateLines(text, slackMaxSectionLength)}},
+		)
+	}
+
+	return p, nil
+}
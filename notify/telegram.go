@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	telegramBotTokenEnvKey   = "TELEGRAM_BOT_TOKEN"
+	telegramChatIDEnvKey     = "TELEGRAM_CHAT_ID"
+	telegramBaseURLEnvKey    = "TELEGRAM_API_BASE_URL"
+	defaultTelegramBaseURL   = "https://api.telegram.org"
+	telegramMaxMessageLength = 4096
+)
+
+// Telegram sends findings as a message through the Telegram Bot API.
+type Telegram struct {
+	httpClient     httpClient
+	baseURL        string
+	botToken       string
+	chatID         string
+	productURLBase string
+	template       *template.Template
+}
+
+type telegramPayload struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+var telegramFuncs = template.FuncMap{
+	"esc": html.EscapeString,
+	"link": func(url, text string) string {
+		return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + "</a>"
+	},
+}
+
+// NewTelegram returns nil when no bot token or chat is configured.
+func NewTelegram() (*Telegram, error) {
+	return newTelegram(os.Getenv)
+}
+
+func newTelegram(getenv func(string) string) (*Telegram, error) {
+	botToken := getenv(telegramBotTokenEnvKey)
+	chatID := getenv(telegramChatIDEnvKey)
+	if botToken == "" || chatID == "" {
+		return nil, nil
+	}
+
+	t, err := loadChatTemplate("telegram", telegramFuncs)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := getenv(telegramBaseURLEnvKey)
+	if baseURL == "" {
+		baseURL = defaultTelegramBaseURL
+	}
+
+	return &Telegram{
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		botToken:       botToken,
+		chatID:         chatID,
+		productURLBase: getenv(productURLBaseEnvKey),
+		template:       t,
+	}, nil
+}
+
+func (t *Telegram) Notify(ctx context.Context, report *checker.Report) error {
+	if len(report.Findings) == 0 {
+		return nil
+	}
+
+	payload, err := t.payload(report)
+	if err != nil {
+		return err
+	}
+	return t.send(ctx, payload)
+}
+
+// NotifyOps sends the operational alert a as a plain message.
+func (t *Telegram) NotifyOps(ctx context.Context, a OpsAlert) error {
+	text := "<b>" + html.EscapeString(a.Summary()) + "</b>"
+	if a.Message != "" {
+		text += "\n" + html.EscapeString(a.Message)
+	}
+
+	return t.send(ctx, &telegramPayload{
+		ChatID:                t.chatID,
+		Text:                  text,
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	})
+}
+
+func (t *Telegram) send(ctx context.Context, payload *telegramPayload) error {
+	err := postJSON(ctx, t.httpClient, t.baseURL+"/bot"+t.botToken+"/sendMessage", payload)
+
+	// the URL holds the bot token, keep it out of the logs
+	if urlErr, ok := err.(*url.Error); ok {
+		return fmt.Errorf("telegram sendMessage: %w", urlErr.Err)
+	}
+	return err
+}
+
+func (t *Telegram) payload(report *checker.Report) (*telegramPayload, error) {
+	m := newChatMessage(report, t.productURLBase)
+
+	header, err := execute(t.template, "header", m)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []string{header}
+	for _, g := range m.Groups {
+		text, err := execute(t.template, "group", g)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, text)
+	}
+
+	// cut whole groups, so no HTML tag is left open
+	text := ""
+	for _, part := range parts {
+		if len(text)+len(part)+2 > telegramMaxMessageLength {
+			break
+		}
+		if text != "" {
+			text += "\n\n"
+		}
+		text += part
+	}
+
+	return &telegramPayload{
+		ChatID:                t.chatID,
+		Text:                  text,
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	}, nil
+}
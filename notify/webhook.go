@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/backoff"
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+const (
+	webhookURLsEnvKey        = "WEBHOOK_URLS"
+	webhookSecretEnvKey      = "WEBHOOK_SECRET"
+	webhookBatchSizeEnvKey   = "WEBHOOK_BATCH_SIZE"
+	webhookMaxAttemptsEnvKey = "WEBHOOK_MAX_ATTEMPTS"
+)
+
+const (
+	defaultWebhookBatchSize   = 100
+	defaultWebhookMaxAttempts = 3
+	webhookRetryBaseDelay     = time.Second
+	webhookRetryMaxDelay      = 30 * time.Second
+)
+
+// SignatureHeader holds the hex HMAC-SHA256 of the request body, keyed with
+// the webhook secret, as "sha256=<hex>".
+const SignatureHeader = "X-Signature-256"
+
+// Webhook POSTs findings as JSON to every configured URL, in batches.
+type Webhook struct {
+	httpClient  httpClient
+	urls        []string
+	secret      string
+	batchSize   int
+	maxAttempts int
+}
+
+// NewWebhook returns nil when no webhook URL is configured.
+func NewWebhook() *Webhook {
+	return newWebhook(os.Getenv)
+}
+
+func newWebhook(getenv func(string) string) *Webhook {
+	urls := splitList(getenv(webhookURLsEnvKey))
+	if len(urls) == 0 {
+		return nil
+	}
+
+	return &Webhook{
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		urls:        urls,
+		secret:      getenv(webhookSecretEnvKey),
+		batchSize:   atoi(getenv(webhookBatchSizeEnvKey), defaultWebhookBatchSize),
+		maxAttempts: atoi(getenv(webhookMaxAttemptsEnvKey), defaultWebhookMaxAttempts),
+	}
+}
+
+// WebhookPayload is the body of every webhook request.
+type WebhookPayload struct {
+	Partner   string            `json:"partner,omitempty"`
+	StartedAt time.Time         `json:"started_at"`
+	Batch     int               `json:"batch"`
+	Batches   int               `json:"batches"`
+	Findings  []checker.Finding `json:"findings"`
+}
+
+// Notify delivers every batch to every URL, even when some of them fail,
+// and returns the failures together.
+func (w *Webhook) Notify(ctx context.Context, report *checker.Report) error {
+	if len(report.Findings) == 0 {
+		return nil
+	}
+
+	var msgs []string
+	batches := (len(report.Findings) + w.batchSize - 1) / w.batchSize
+	for i := 0; i < batches; i++ {
+		end := (i + 1) * w.batchSize
+		if end > len(report.Findings) {
+			end = len(report.Findings)
+		}
+
+		body, err := json.Marshal(WebhookPayload{
+			Partner:   report.Partner,
+			StartedAt: report.StartedAt,
+			Batch:     i + 1,
+			Batches:   batches,
+			Findings:  report.Findings[i*w.batchSize : end],
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, url := range w.urls {
+			if err := w.post(ctx, url, body); err != nil {
+				msgs = append(msgs, fmt.Sprintf("webhook %s, batch %d: %v", url, i+1, err))
+			}
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// NotifyOps POSTs the operational alert a as JSON to every configured URL,
+// even when some of them fail.
+func (w *Webhook) NotifyOps(ctx context.Context, a OpsAlert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	var msgs []string
+	for _, url := range w.urls {
+		if err := w.post(ctx, url, body); err != nil {
+			msgs = append(msgs, fmt.Sprintf("webhook %s: %v", url, err))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// post sends body to url, retrying on network errors, 429 and 5xx.
+func (w *Webhook) post(ctx context.Context, url string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= w.maxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, backoff.Delay(attempt-1, webhookRetryBaseDelay, webhookRetryMaxDelay)); err != nil {
+				return err
+			}
+		}
+
+		var retry bool
+		retry, err = w.send(ctx, url, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (w *Webhook) send(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, body))
+	}
+
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+		return retry, fmt.Errorf("got this status code: %d", res.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret, so receivers
+// can verify the SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// atoi returns def when s isn't a positive number.
+func atoi(s string, def int) int {
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	origSleep := sleep
+	defer func() { sleep = origSleep }()
+	sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	secret := "sample secret"
+
+	var mu sync.Mutex
+	var payloads []WebhookPayload
+	failures := 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign(secret, body); got != want {
+			t.Errorf("signature header = %v, want %v", got, want)
+		}
+
+		var p WebhookPayload
+		json.Unmarshal(body, &p)
+		payloads = append(payloads, p)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{
+		httpClient:  ts.Client(),
+		urls:        []string{ts.URL},
+		secret:      secret,
+		batchSize:   2,
+		maxAttempts: 3,
+	}
+
+	report := &checker.Report{
+		Partner: "sample partner",
+		Findings: []checker.Finding{
+			{Kind: checker.KindPriceChanged, Row: 1},
+			{Kind: checker.KindPriceChanged, Row: 2},
+			{Kind: checker.KindStockLevelChanged, Row: 3},
+		},
+	}
+
+	if err := w.Notify(context.Background(), report); err != nil {
+		t.Fatalf("Webhook.Notify() error = %v", err)
+	}
+
+	if len(payloads) != 2 {
+		t.Fatalf("webhook requests = %v, want %v", len(payloads), 2)
+	}
+	for i, want := range []int{2, 1} {
+		if got := len(payloads[i].Findings); got != want {
+			t.Errorf("batch %d findings = %v, want %v", i+1, got, want)
+		}
+		if payloads[i].Batches != 2 || payloads[i].Partner != report.Partner {
+			t.Errorf("batch %d = %+v, want 2 batches of %v", i+1, payloads[i], report.Partner)
+		}
+	}
+}
+
+func TestWebhook_Notify_clientError(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{httpClient: ts.Client(), urls: []string{ts.URL}, batchSize: 10, maxAttempts: 3}
+	report := &checker.Report{Findings: []checker.Finding{{Kind: checker.KindPriceChanged}}}
+
+	if err := w.Notify(context.Background(), report); err == nil {
+		t.Errorf("Webhook.Notify() error = nil, want error")
+	}
+	if requests != 1 {
+		t.Errorf("webhook requests = %v, want %v", requests, 1)
+	}
+}
+
+func TestWebhook_Notify_failingURL(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	var mu sync.Mutex
+	batches := 0
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		batches++
+		mu.Unlock()
+	}))
+	defer ok.Close()
+
+	w := &Webhook{httpClient: http.DefaultClient, urls: []string{failing.URL, ok.URL}, batchSize: 1, maxAttempts: 1}
+	report := &checker.Report{Findings: []checker.Finding{{Kind: checker.KindPriceChanged}, {Kind: checker.KindDiscontinued}}}
+
+	if err := w.Notify(context.Background(), report); err == nil {
+		t.Errorf("Webhook.Notify() error = nil, want error")
+	}
+	if batches != 2 {
+		t.Errorf("batches delivered to the working URL = %v, want %v", batches, 2)
+	}
+}
@@ -0,0 +1,179 @@
+package partner
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	breakerFailureThresholdEnvKey = "BREAKER_FAILURE_THRESHOLD"
+	breakerFailureRateEnvKey      = "BREAKER_FAILURE_RATE"
+	breakerWindowEnvKey           = "BREAKER_WINDOW"
+	breakerCooldownEnvKey         = "BREAKER_COOLDOWN"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerWindow           = 20
+	defaultBreakerCooldown         = 30 * time.Second
+)
+
+// ErrPartnerUnavailable is returned without calling the partner while its
+// circuit breaker is open.
+var ErrPartnerUnavailable = errors.New("partner unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breakerClient stops calling a partner that keeps failing. The breaker
+// opens after failureThreshold consecutive failures, or when more than
+// failureRate of the last window requests failed. After cooldown a single
+// probe request is let through; it closes the breaker again on success.
+type breakerClient struct {
+	httpClient       httpClient
+	failureThreshold int
+	failureRate      float64
+	cooldown         time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	window   []bool
+	next     int
+	filled   bool
+	openedAt time.Time
+}
+
+func newBreakerClient(c httpClient) *breakerClient {
+	rate, err := strconv.ParseFloat(os.Getenv(breakerFailureRateEnvKey), 64)
+	if err != nil || rate < 0 || rate > 1 {
+		rate = 0
+	}
+
+	return &breakerClient{
+		httpClient:       c,
+		failureThreshold: envInt(breakerFailureThresholdEnvKey, defaultBreakerFailureThreshold),
+		failureRate:      rate,
+		cooldown:         envDuration(breakerCooldownEnvKey, defaultBreakerCooldown),
+		window:           make([]bool, envInt(breakerWindowEnvKey, defaultBreakerWindow)),
+	}
+}
+
+func (c *breakerClient) Do(req *http.Request) (*http.Response, error) {
+	if !c.allow() {
+		return nil, ErrPartnerUnavailable
+	}
+
+	// Calls the caller gave up on, by canceling ctx or by its deadline, and
+	// calls the budget stopped say nothing about the partner. A client side
+	// REQUEST_TIMEOUT still counts as a failure.
+	res, err := c.httpClient.Do(req)
+	if err != nil && (req.Context().Err() != nil || errors.Is(err, ErrBudgetExhausted)) {
+		c.abandon()
+		return res, err
+	}
+
+	c.record(err != nil || res.StatusCode >= 500)
+	return res, err
+}
+
+func (c *breakerClient) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case breakerOpen:
+		if now().Sub(c.openedAt) < c.cooldown {
+			return false
+		}
+		c.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// only the probe request goes through
+		return false
+	}
+	return true
+}
+
+// abandon ends a probe that got no answer by opening the breaker again, so
+// another probe is let through after a fresh cooldown.
+func (c *breakerClient) abandon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == breakerHalfOpen {
+		c.open()
+	}
+}
+
+func (c *breakerClient) record(failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == breakerHalfOpen {
+		if failed {
+			c.open()
+		} else {
+			c.reset()
+		}
+		return
+	}
+
+	if failed {
+		c.failures++
+	} else {
+		c.failures = 0
+	}
+
+	if len(c.window) > 0 {
+		c.window[c.next] = failed
+		c.next = (c.next + 1) % len(c.window)
+		if c.next == 0 {
+			c.filled = true
+		}
+	}
+
+	if c.failures >= c.failureThreshold || c.failureRateExceeded() {
+		c.open()
+	}
+}
+
+// failureRateExceeded only judges a full window, so a single early failure
+// doesn't open the breaker.
+func (c *breakerClient) failureRateExceeded() bool {
+	if c.failureRate == 0 || !c.filled {
+		return false
+	}
+
+	failed := 0
+	for _, f := range c.window {
+		if f {
+			failed++
+		}
+	}
+	return float64(failed)/float64(len(c.window)) > c.failureRate
+}
+
+func (c *breakerClient) open() {
+	c.state = breakerOpen
+	c.openedAt = now()
+}
+
+func (c *breakerClient) reset() {
+	c.state = breakerClosed
+	c.failures = 0
+	c.next = 0
+	c.filled = false
+	for i := range c.window {
+		c.window[i] = false
+	}
+}
@@ -0,0 +1,129 @@
+package partner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBreakerClient_Do(t *testing.T) {
+	current := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return current }
+
+	newReq := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+		return req
+	}
+	okRes := func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBuffer([]byte("")))}
+	}
+
+	t.Run("opens after consecutive failures and probes after cooldown", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(nil, fmt.Errorf("connection refused")).Times(2)
+		httpClient.On("Do", mock.Anything).Return(okRes(), nil).Once()
+		c := &breakerClient{httpClient: httpClient, failureThreshold: 2, cooldown: time.Minute}
+
+		c.Do(newReq())
+		c.Do(newReq())
+		if _, err := c.Do(newReq()); err != ErrPartnerUnavailable {
+			t.Errorf("breakerClient.Do() error = %v, want %v", err, ErrPartnerUnavailable)
+		}
+
+		current = current.Add(time.Minute)
+		if _, err := c.Do(newReq()); err != nil {
+			t.Errorf("breakerClient.Do() probe error = %v", err)
+		}
+		if c.state != breakerClosed {
+			t.Errorf("breakerClient.state = %v, want %v", c.state, breakerClosed)
+		}
+		httpClient.AssertNumberOfCalls(t, "Do", 3)
+	})
+
+	t.Run("reopens when the probe fails", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusBadGateway}, nil)
+		c := &breakerClient{httpClient: httpClient, failureThreshold: 1, cooldown: time.Minute}
+
+		c.Do(newReq())
+		current = current.Add(time.Minute)
+		c.Do(newReq())
+
+		if c.state != breakerOpen {
+			t.Errorf("breakerClient.state = %v, want %v", c.state, breakerOpen)
+		}
+	})
+
+	t.Run("opens when the failure rate is exceeded", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		failRes := &http.Response{StatusCode: http.StatusInternalServerError}
+		for _, res := range []*http.Response{failRes, okRes(), failRes, failRes} {
+			httpClient.On("Do", mock.Anything).Return(res, nil).Once()
+		}
+		c := &breakerClient{
+			httpClient:       httpClient,
+			failureThreshold: 10,
+			failureRate:      0.5,
+			cooldown:         time.Minute,
+			window:           make([]bool, 4),
+		}
+
+		for i := 0; i < 4; i++ {
+			c.Do(newReq())
+		}
+
+		if c.state != breakerOpen {
+			t.Errorf("breakerClient.state = %v, want %v", c.state, breakerOpen)
+		}
+	})
+
+	t.Run("opens again after a canceled probe", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(nil, fmt.Errorf("connection refused")).Once()
+		httpClient.On("Do", mock.Anything).Return(nil, context.Canceled).Once()
+		httpClient.On("Do", mock.Anything).Return(okRes(), nil).Once()
+		c := &breakerClient{httpClient: httpClient, failureThreshold: 1, cooldown: time.Minute}
+
+		c.Do(newReq())
+		current = current.Add(time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := c.Do(newReq().WithContext(ctx)); err != context.Canceled {
+			t.Errorf("breakerClient.Do() probe error = %v, want %v", err, context.Canceled)
+		}
+		if c.state != breakerOpen {
+			t.Errorf("breakerClient.state after a canceled probe = %v, want %v", c.state, breakerOpen)
+		}
+
+		current = current.Add(time.Minute)
+		if _, err := c.Do(newReq()); err != nil {
+			t.Errorf("breakerClient.Do() next probe error = %v", err)
+		}
+		if c.state != breakerClosed {
+			t.Errorf("breakerClient.state = %v, want %v", c.state, breakerClosed)
+		}
+	})
+
+	t.Run("doesn't count the caller's deadline as a failure", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(nil, context.DeadlineExceeded)
+		c := &breakerClient{httpClient: httpClient, failureThreshold: 1, cooldown: time.Minute}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		c.Do(newReq().WithContext(ctx))
+
+		if c.state != breakerClosed {
+			t.Errorf("breakerClient.state = %v, want %v", c.state, breakerClosed)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package partner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	cassetteModeEnvKey   = "CASSETTE_MODE"
+	cassettePathEnvKey   = "CASSETTE_PATH"
+	cassetteStrictEnvKey = "CASSETTE_STRICT"
+)
+
+const (
+	CassettePassthrough = "passthrough"
+	CassetteRecord      = "record"
+	CassetteReplay      = "replay"
+)
+
+const redacted = "REDACTED"
+
+// redactedHeaders and redactedFields never make it into a cassette file.
+var (
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	redactedFields  = map[string]bool{"username": true, "password": true, "token": true}
+)
+
+var ErrUnexpectedRequest = errors.New("cassette: unexpected request")
+
+type cassette struct {
+	Version      int           `json:"version"`
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  recordedRequest   `json:"request"`
+	Response *recordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	ErrorIs  string            `json:"error_is,omitempty"`
+}
+
+// recordedErrors are the errors a cassette keeps the identity of, so a
+// replayed budget or breaker stop is handled like the recorded one. Non 2xx
+// responses are recorded as responses and replay as HTTPError or
+// DecodeError as they did live.
+var recordedErrors = map[string]error{
+	"budget_exhausted":    ErrBudgetExhausted,
+	"partner_unavailable": ErrPartnerUnavailable,
+}
+
+// replayedError is a recorded error that still matches the error it
+// wrapped when it was recorded.
+type replayedError struct {
+	msg string
+	err error
+}
+
+func (e *replayedError) Error() string {
+	return e.msg
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.err
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// cassetteClient records the partner traffic to a file, or replays it from
+// one without touching the network. It wraps the rate limiter, retries and
+// breaker, so it records what a call ended with and replays skip them.
+// Requests are matched on method, URL and redacted body; in strict mode they
+// also have to come in recorded order. A recording is written on save.
+type cassetteClient struct {
+	httpClient httpClient
+	mode       string
+	path       string
+	strict     bool
+
+	mu       sync.Mutex
+	cassette cassette
+	used     []bool
+}
+
+// newCassetteClient wraps c according to the cassette env. In passthrough
+// mode there is no cassette and it returns nil.
+func newCassetteClient(c httpClient) (*cassetteClient, error) {
+	mode := os.Getenv(cassetteModeEnvKey)
+	if mode == "" || mode == CassettePassthrough {
+		return nil, nil
+	}
+
+	strict, _ := strconv.ParseBool(os.Getenv(cassetteStrictEnvKey))
+	cc := &cassetteClient{
+		httpClient: c,
+		mode:       mode,
+		path:       os.Getenv(cassettePathEnvKey),
+		strict:     strict,
+		cassette:   cassette{Version: 1},
+	}
+
+	switch mode {
+	case CassetteRecord:
+		return cc, nil
+	case CassetteReplay:
+		b, err := ioutil.ReadFile(cc.path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(b, &cc.cassette); err != nil {
+			return nil, fmt.Errorf("reading cassette %s: %w", cc.path, err)
+		}
+		cc.used = make([]bool, len(cc.cassette.Interactions))
+		return cc, nil
+	}
+
+	return nil, fmt.Errorf("unknown cassette mode: %s", mode)
+}
+
+func (c *cassetteClient) Do(req *http.Request) (*http.Response, error) {
+	rr, err := newRecordedRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.mode == CassetteReplay {
+		return c.replay(req, rr)
+	}
+	return c.record(req, rr)
+}
+
+func (c *cassetteClient) record(req *http.Request, rr recordedRequest) (*http.Response, error) {
+	in := interaction{Request: rr}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil && req.Context().Err() != nil {
+		// the caller gave up; the partner had nothing to do with it
+		return res, err
+	}
+	if err != nil {
+		in.Error = err.Error()
+		for name, target := range recordedErrors {
+			if errors.Is(err, target) {
+				in.ErrorIs = name
+			}
+		}
+	} else {
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		in.Response = &recordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     redactHeader(res.Header),
+			Body:       redactBody(body),
+		}
+	}
+
+	c.mu.Lock()
+	c.cassette.Interactions = append(c.cassette.Interactions, in)
+	c.mu.Unlock()
+
+	return res, err
+}
+
+func (c *cassetteClient) replay(req *http.Request, rr recordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, in := range c.cassette.Interactions {
+		if c.used[i] {
+			continue
+		}
+
+		if in.Request.Method != rr.Method || in.Request.URL != rr.URL || in.Request.Body != rr.Body {
+			if c.strict {
+				break
+			}
+			continue
+		}
+
+		c.used[i] = true
+		if in.Response == nil {
+			return nil, &replayedError{msg: in.Error, err: recordedErrors[in.ErrorIs]}
+		}
+
+		return &http.Response{
+			StatusCode: in.Response.StatusCode,
+			Status:     fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			Header:     in.Response.Header.Clone(),
+			Body:       ioutil.NopCloser(bytes.NewBufferString(in.Response.Body)),
+			Request:    req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrUnexpectedRequest, rr.Method, rr.URL)
+}
+
+// save writes the recorded interactions to the cassette file. Replaying
+// cassettes are left alone.
+func (c *cassetteClient) save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.MarshalIndent(c.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, b, 0600)
+}
+
+func newRecordedRequest(req *http.Request) (recordedRequest, error) {
+	rr := recordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: redactHeader(req.Header),
+	}
+
+	if req.Body == nil {
+		return rr, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return rr, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	rr.Body = redactBody(body)
+
+	return rr, nil
+}
+
+func redactHeader(h http.Header) http.Header {
+	res := h.Clone()
+	for _, k := range redactedHeaders {
+		if res.Get(k) != "" {
+			res.Set(k, redacted)
+		}
+	}
+	return res
+}
+
+// redactBody replaces credentials in JSON bodies. Anything that isn't JSON
+// is kept as is.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+
+	b, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			if redactedFields[k] {
+				v[k] = redacted
+			} else {
+				v[k] = redactValue(vv)
+			}
+		}
+	case []interface{}:
+		for i, vv := range v {
+			v[i] = redactValue(vv)
+		}
+	}
+	return v
+}
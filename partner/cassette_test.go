@@ -0,0 +1,187 @@
+package partner
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/fakepartner"
+	"github.com/andrysds/dropship-checker/product"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCassetteClient(t *testing.T) {
+	mockProduct := &product.Product{
+		Name:     "sample name",
+		Variants: []product.Variant{{Name: "sample name", Price: 1000, Stock: 10}},
+	}
+
+	s := fakepartner.New(map[string]*product.Product{"sample-slug": mockProduct})
+	s.Username = "sample username"
+	s.Password = "sample password"
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	os.Setenv(cassettePathEnvKey, path)
+	defer os.Unsetenv(cassettePathEnvKey)
+	defer os.Unsetenv(cassetteModeEnvKey)
+
+	newTestPartner := func(mode string, c httpClient) *Partner {
+		os.Setenv(cassetteModeEnvKey, mode)
+		cc, err := newCassetteClient(c)
+		if err != nil {
+			t.Fatalf("newCassetteClient() error = %v", err)
+		}
+		return &Partner{
+			httpClient:        cc,
+			cassette:          cc,
+			username:          s.Username,
+			password:          s.Password,
+			loginUrl:          ts.URL + fakepartner.LoginPath,
+			getProductBaseUrl: ts.URL + fakepartner.ProductBaseURL,
+		}
+	}
+
+	t.Run("record", func(t *testing.T) {
+		p := newTestPartner(CassetteRecord, &http.Client{})
+		if err := p.Login(); err != nil {
+			t.Fatalf("Partner.Login() error = %v", err)
+		}
+		if _, err := p.GetProduct("sample-slug"); err != nil {
+			t.Fatalf("Partner.GetProduct() error = %v", err)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("cassette written before Partner.Close(), stat error = %v", err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatalf("Partner.Close() error = %v", err)
+		}
+
+		b, _ := ioutil.ReadFile(path)
+		for _, secret := range []string{s.Username, s.Password, p.authToken} {
+			if strings.Contains(string(b), secret) {
+				t.Errorf("cassette contains %q", secret)
+			}
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		p := newTestPartner(CassetteReplay, &mockHttpClient{})
+		if err := p.Login(); err != nil {
+			t.Fatalf("Partner.Login() error = %v", err)
+		}
+
+		got, err := p.GetProduct("sample-slug")
+		if err != nil {
+			t.Fatalf("Partner.GetProduct() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, mockProduct) {
+			t.Errorf("Partner.GetProduct() = %v, want %v", got, mockProduct)
+		}
+
+		if _, err := p.GetProduct("sample-slug"); !errors.Is(err, ErrUnexpectedRequest) {
+			t.Errorf("Partner.GetProduct() error = %v, want %v", err, ErrUnexpectedRequest)
+		}
+	})
+
+	t.Run("replay skips retries", func(t *testing.T) {
+		os.Setenv(cassetteModeEnvKey, CassetteReplay)
+		p, err := NewPartner(nil)
+		if err != nil {
+			t.Fatalf("NewPartner() error = %v", err)
+		}
+		p.getProductBaseUrl = ts.URL + fakepartner.ProductBaseURL
+
+		origSleep := sleep
+		defer func() { sleep = origSleep }()
+		sleep = func(ctx context.Context, d time.Duration) error {
+			t.Errorf("replayed request retried")
+			return nil
+		}
+
+		if _, err := p.GetProduct("missing-slug"); !errors.Is(err, ErrUnexpectedRequest) {
+			t.Errorf("Partner.GetProduct() error = %v, want %v", err, ErrUnexpectedRequest)
+		}
+	})
+
+	t.Run("strict replay", func(t *testing.T) {
+		os.Setenv(cassetteStrictEnvKey, "true")
+		defer os.Unsetenv(cassetteStrictEnvKey)
+
+		p := newTestPartner(CassetteReplay, &mockHttpClient{})
+		if _, err := p.GetProduct("sample-slug"); !errors.Is(err, ErrUnexpectedRequest) {
+			t.Errorf("Partner.GetProduct() before Login error = %v, want %v", err, ErrUnexpectedRequest)
+		}
+	})
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "login request",
+			body: `{"password":"secret","username":"admin"}`,
+			want: `{"password":"REDACTED","username":"REDACTED"}`,
+		},
+		{
+			name: "nested token",
+			body: `{"data":{"token":"abc"}}`,
+			want: `{"data":{"token":"REDACTED"}}`,
+		},
+		{
+			name: "not json",
+			body: "plain text",
+			want: "plain text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.body)); got != tt.want {
+				t.Errorf("redactBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCassetteClient_errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	os.Setenv(cassettePathEnvKey, path)
+	defer os.Unsetenv(cassettePathEnvKey)
+	defer os.Unsetenv(cassetteModeEnvKey)
+
+	os.Setenv(cassetteModeEnvKey, CassetteRecord)
+	httpClient := &mockHttpClient{}
+	httpClient.On("Do", mock.Anything).Return(nil, ErrPartnerUnavailable)
+	cc, err := newCassetteClient(httpClient)
+	if err != nil {
+		t.Fatalf("newCassetteClient() error = %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+	cc.Do(req)
+	if err := cc.save(); err != nil {
+		t.Fatalf("cassetteClient.save() error = %v", err)
+	}
+
+	os.Setenv(cassetteModeEnvKey, CassetteReplay)
+	cc, err = newCassetteClient(&mockHttpClient{})
+	if err != nil {
+		t.Fatalf("newCassetteClient() error = %v", err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+	if _, err := cc.Do(req); !errors.Is(err, ErrPartnerUnavailable) {
+		t.Errorf("cassetteClient.Do() replayed error = %v, want %v", err, ErrPartnerUnavailable)
+	}
+}
@@ -0,0 +1,40 @@
+package partner_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/fakepartner"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/partnertest"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestPartner_conformance(t *testing.T) {
+	partnertest.RunConformance(t, func(t *testing.T, products map[string]*product.Product) checker.ContextPartner {
+		s := fakepartner.New(products)
+		s.Username = "admin"
+		s.Password = "secret"
+		ts := httptest.NewServer(s)
+		t.Cleanup(ts.Close)
+
+		env := map[string]string{
+			"USERNAME":             s.Username,
+			"PASSWORD":             s.Password,
+			"LOGIN_URL":            ts.URL + fakepartner.LoginPath,
+			"GET_PRODUCT_BASE_URL": ts.URL + fakepartner.ProductBaseURL,
+		}
+		for k, v := range env {
+			os.Setenv(k, v)
+			defer os.Unsetenv(k)
+		}
+
+		p, err := partner.NewPartner(nil)
+		if err != nil {
+			t.Fatalf("NewPartner() error = %v", err)
+		}
+		return p
+	})
+}
@@ -0,0 +1,79 @@
+package partner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrRateLimited     = errors.New("rate limited")
+)
+
+// bodySnippetLimit is how much of a response body errors keep.
+const bodySnippetLimit = 512
+
+// HTTPError is returned when the partner answers with a non 2xx status code.
+// It matches ErrProductNotFound, ErrUnauthorized and ErrRateLimited with
+// errors.Is depending on the status code.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func newHTTPError(res *http.Response) *HTTPError {
+	return &HTTPError{StatusCode: res.StatusCode, Body: readSnippet(res.Body)}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("got this status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("got this status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrProductNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// DecodeError is returned when a 2xx response body isn't what the partner
+// API is supposed to send.
+type DecodeError struct {
+	Err  error
+	Body string
+}
+
+func newDecodeError(err error, body []byte) *DecodeError {
+	if len(body) > bodySnippetLimit {
+		body = body[:bodySnippetLimit]
+	}
+	return &DecodeError{Err: err, Body: string(body)}
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("malformed response: %v, body: %s", e.Err, e.Body)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func readSnippet(body io.Reader) string {
+	if body == nil {
+		return ""
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(body, bodySnippetLimit))
+	return string(b)
+}
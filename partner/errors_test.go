@@ -0,0 +1,43 @@
+package partner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPError_Is(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		target     error
+		want       bool
+	}{
+		{name: "404 is product not found", statusCode: http.StatusNotFound, target: ErrProductNotFound, want: true},
+		{name: "410 is product not found", statusCode: http.StatusGone, target: ErrProductNotFound, want: true},
+		{name: "401 is unauthorized", statusCode: http.StatusUnauthorized, target: ErrUnauthorized, want: true},
+		{name: "429 is rate limited", statusCode: http.StatusTooManyRequests, target: ErrRateLimited, want: true},
+		{name: "500 is not product not found", statusCode: http.StatusInternalServerError, target: ErrProductNotFound, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &HTTPError{StatusCode: tt.statusCode})
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("errors.Is(HTTPError, %v) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeError_Unwrap(t *testing.T) {
+	cause := errors.New("unexpected end of JSON input")
+	err := newDecodeError(cause, []byte("{"))
+
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(DecodeError, cause) = false, want true")
+	}
+	if err.Body != "{" {
+		t.Errorf("DecodeError.Body = %v, want %v", err.Body, "{")
+	}
+}
@@ -2,11 +2,15 @@ package partner
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/andrysds/dropship-checker/product"
 )
@@ -16,8 +20,11 @@ const (
 	passwordEnvKey          = "PASSWORD"
 	loginUrlEnvKey          = "LOGIN_URL"
 	getProductBaseUrlEnvKey = "GET_PRODUCT_BASE_URL"
+	requestTimeoutEnvKey    = "REQUEST_TIMEOUT"
 )
 
+const defaultRequestTimeout = 30 * time.Second
+
 type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -29,16 +36,45 @@ type Partner struct {
 	password          string
 	loginUrl          string
 	getProductBaseUrl string
+	cassette          *cassetteClient
+
+	mu sync.Mutex
 }
 
-func NewPartner() *Partner {
+// NewPartner returns a Partner configured from the env, or the error of
+// setting up its cassette. Every HTTP request, retries included, goes through
+// transport; nil means http.DefaultTransport. A recording Partner writes its
+// cassette on Close.
+func NewPartner(transport http.RoundTripper) (*Partner, error) {
+	var c httpClient = newBreakerClient(newRetryClient(newRateLimitClient(
+		&http.Client{Transport: transport, Timeout: envDuration(requestTimeoutEnvKey, defaultRequestTimeout)},
+	)))
+
+	cassette, err := newCassetteClient(c)
+	if err != nil {
+		return nil, err
+	}
+	if cassette != nil {
+		c = cassette
+	}
+
 	return &Partner{
-		httpClient:        &http.Client{},
+		httpClient:        c,
 		username:          os.Getenv(usernameEnvKey),
 		password:          os.Getenv(passwordEnvKey),
 		loginUrl:          os.Getenv(loginUrlEnvKey),
 		getProductBaseUrl: os.Getenv(getProductBaseUrlEnvKey),
+		cassette:          cassette,
+	}, nil
+}
+
+// Close writes the cassette of a recording Partner. Other Partners have
+// nothing to close.
+func (p *Partner) Close() error {
+	if p.cassette == nil {
+		return nil
 	}
+	return p.cassette.save()
 }
 
 type loginResponse struct {
@@ -48,6 +84,10 @@ type loginResponse struct {
 }
 
 func (p *Partner) Login() error {
+	return p.LoginContext(context.Background())
+}
+
+func (p *Partner) LoginContext(ctx context.Context) error {
 	jsonBody, err := json.Marshal(
 		map[string]string{
 			"username": p.username,
@@ -59,7 +99,7 @@ func (p *Partner) Login() error {
 	}
 	reqBody := bytes.NewBuffer(jsonBody)
 
-	req, err := http.NewRequest(http.MethodPost, p.loginUrl, reqBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.loginUrl, reqBody)
 	if err != nil {
 		return err
 	}
@@ -71,7 +111,7 @@ func (p *Partner) Login() error {
 	}
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("got this status code: %d", res.StatusCode)
+		return newHTTPError(res)
 	}
 
 	resBody, err := ioutil.ReadAll(res.Body)
@@ -82,14 +122,16 @@ func (p *Partner) Login() error {
 	var loginRes *loginResponse
 	err = json.Unmarshal(resBody, &loginRes)
 	if err != nil {
-		return err
+		return newDecodeError(err, resBody)
 	}
 
 	if loginRes.Data.Token == "" {
 		return fmt.Errorf("got empty token, resBody:%v,", string(resBody))
 	}
 
+	p.mu.Lock()
 	p.authToken = loginRes.Data.Token
+	p.mu.Unlock()
 	return nil
 }
 
@@ -98,12 +140,18 @@ type getProductResponse struct {
 }
 
 func (p *Partner) GetProduct(slug string) (*product.Product, error) {
-	req, err := http.NewRequest(http.MethodGet, p.getProductBaseUrl+slug, nil)
+	return p.GetProductContext(context.Background(), slug)
+}
+
+func (p *Partner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.getProductBaseUrl+slug, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	p.mu.Lock()
 	req.Header.Set("Authorization", p.authToken)
+	p.mu.Unlock()
 
 	res, err := p.httpClient.Do(req)
 	if err != nil {
@@ -112,7 +160,7 @@ func (p *Partner) GetProduct(slug string) (*product.Product, error) {
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, fmt.Errorf("got this status code: %d", res.StatusCode)
+		return nil, newHTTPError(res)
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
@@ -121,6 +169,13 @@ func (p *Partner) GetProduct(slug string) (*product.Product, error) {
 	}
 
 	var pp getProductResponse
-	err = json.Unmarshal(body, &pp)
-	return pp.Data, err
+	if err := json.Unmarshal(body, &pp); err != nil {
+		return nil, newDecodeError(err, body)
+	}
+
+	if pp.Data == nil {
+		return nil, newDecodeError(errors.New("missing product data"), body)
+	}
+
+	return pp.Data, nil
 }
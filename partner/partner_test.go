@@ -30,18 +30,33 @@ func TestNewPartner(t *testing.T) {
 	defer os.Unsetenv(getProductBaseUrlEnvKey)
 
 	want := &Partner{
-		httpClient:        &http.Client{},
+		httpClient:        newBreakerClient(newRetryClient(newRateLimitClient(&http.Client{Timeout: defaultRequestTimeout}))),
 		authToken:         "",
 		username:          mockUsername,
 		password:          mockPassword,
 		loginUrl:          mockLoginUrl,
 		getProductBaseUrl: mockGetProductBaseUrl,
 	}
-	if got := NewPartner(); !reflect.DeepEqual(got, want) {
+	got, err := NewPartner(nil)
+	if err != nil {
+		t.Fatalf("NewPartner() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
 		t.Errorf("NewPartner() = %v, want %v", got, want)
 	}
 }
 
+func TestNewPartner_cassetteError(t *testing.T) {
+	os.Setenv(cassetteModeEnvKey, CassetteReplay)
+	os.Setenv(cassettePathEnvKey, "testdata/missing.json")
+	defer os.Unsetenv(cassetteModeEnvKey)
+	defer os.Unsetenv(cassettePathEnvKey)
+
+	if _, err := NewPartner(nil); err == nil {
+		t.Errorf("NewPartner() with a missing cassette error = nil, want an error")
+	}
+}
+
 func TestPartner_Login(t *testing.T) {
 	mockUsername := "sample username"
 	mockPassword := "sample password"
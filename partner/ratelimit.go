@@ -0,0 +1,192 @@
+package partner
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitRpsEnvKey    = "RATE_LIMIT_RPS"
+	rateLimitBurstEnvKey  = "RATE_LIMIT_BURST"
+	dailyCallBudgetEnvKey = "DAILY_CALL_BUDGET"
+	budgetStatePathEnvKey = "BUDGET_STATE_PATH"
+)
+
+const defaultRateLimitBurst = 1
+
+// ErrBudgetExhausted is returned once the daily call budget of a partner is
+// used up. Requests are not sent until the next day.
+var ErrBudgetExhausted = errors.New("daily call budget exhausted")
+
+// now is a variable so tests can control the limiter's clock.
+var now = time.Now
+
+// rateLimitClient limits requests with a token bucket. The rate is halved
+// every time the partner answers 429 and slowly recovers on success.
+//
+// The calls of the daily budget are counted in BUDGET_STATE_PATH, so CLI and
+// cron runs share the budget of the day. Without it they are counted in
+// memory, and every process gets a full budget.
+type rateLimitClient struct {
+	httpClient  httpClient
+	maxRate     float64
+	burst       float64
+	dailyBudget int
+	statePath   string
+
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	day    string
+	calls  int
+}
+
+func newRateLimitClient(c httpClient) *rateLimitClient {
+	rps, err := strconv.ParseFloat(os.Getenv(rateLimitRpsEnvKey), 64)
+	if err != nil || rps < 0 {
+		rps = 0
+	}
+	burst := float64(envInt(rateLimitBurstEnvKey, defaultRateLimitBurst))
+
+	return &rateLimitClient{
+		httpClient:  c,
+		maxRate:     rps,
+		burst:       burst,
+		dailyBudget: envInt(dailyCallBudgetEnvKey, 0),
+		statePath:   os.Getenv(budgetStatePathEnvKey),
+		rate:        rps,
+		tokens:      burst,
+	}
+}
+
+func (c *rateLimitClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.wait(req); err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err == nil {
+		c.adapt(res.StatusCode)
+	}
+	return res, err
+}
+
+// wait spends one call of the daily budget and blocks until a token is
+// available.
+func (c *rateLimitClient) wait(req *http.Request) error {
+	if err := c.spend(); err != nil {
+		return err
+	}
+
+	if c.maxRate == 0 {
+		return nil
+	}
+
+	for {
+		c.mu.Lock()
+		t := now()
+		if !c.last.IsZero() {
+			c.tokens += t.Sub(c.last).Seconds() * c.rate
+			if c.tokens > c.burst {
+				c.tokens = c.burst
+			}
+		}
+		c.last = t
+
+		if c.tokens >= 1 {
+			c.tokens--
+			c.mu.Unlock()
+			return nil
+		}
+		d := time.Duration((1 - c.tokens) / c.rate * float64(time.Second))
+		c.mu.Unlock()
+
+		if err := sleep(req.Context(), d); err != nil {
+			return err
+		}
+	}
+}
+
+// budgetState is the daily budget count kept in BUDGET_STATE_PATH.
+type budgetState struct {
+	Day   string `json:"day"`
+	Calls int    `json:"calls"`
+}
+
+// spend counts one call against the daily budget, or returns
+// ErrBudgetExhausted when it is used up. The state file is read before every
+// call, so processes sharing it see each other's calls.
+func (c *rateLimitClient) spend() error {
+	if c.dailyBudget <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statePath != "" {
+		var state budgetState
+		b, err := ioutil.ReadFile(c.statePath)
+		if err == nil {
+			err = json.Unmarshal(b, &state)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			log.Println("[WARN] [reading budget state]", err)
+		} else {
+			c.day, c.calls = state.Day, state.Calls
+		}
+	}
+
+	day := now().Format("2006-01-02")
+	if day != c.day {
+		c.day = day
+		c.calls = 0
+	}
+	if c.calls >= c.dailyBudget {
+		return ErrBudgetExhausted
+	}
+	c.calls++
+
+	if c.statePath != "" {
+		b, err := json.Marshal(budgetState{Day: c.day, Calls: c.calls})
+		if err == nil {
+			err = ioutil.WriteFile(c.statePath, b, 0644)
+		}
+		if err != nil {
+			log.Println("[WARN] [saving budget state]", err)
+		}
+	}
+	return nil
+}
+
+// adapt halves the rate on 429 and recovers a tenth of the configured rate
+// on every other response.
+func (c *rateLimitClient) adapt(statusCode int) {
+	if c.maxRate == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if statusCode == http.StatusTooManyRequests {
+		c.rate /= 2
+		if min := c.maxRate / 16; c.rate < min {
+			c.rate = min
+		}
+		return
+	}
+
+	c.rate += c.maxRate / 10
+	if c.rate > c.maxRate {
+		c.rate = c.maxRate
+	}
+}
@@ -0,0 +1,109 @@
+package partner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimitClient_Do(t *testing.T) {
+	current := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	origNow, origSleep := now, sleep
+	defer func() { now, sleep = origNow, origSleep }()
+	now = func() time.Time { return current }
+
+	var slept time.Duration
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		current = current.Add(d)
+		return nil
+	}
+
+	newRes := func(statusCode int) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(bytes.NewBuffer([]byte(""))),
+		}
+	}
+
+	t.Run("waits for tokens once burst is used", func(t *testing.T) {
+		slept = 0
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(newRes(http.StatusOK), nil)
+		c := &rateLimitClient{httpClient: httpClient, maxRate: 2, burst: 2, rate: 2, tokens: 2}
+
+		for i := 0; i < 4; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+			if _, err := c.Do(req); err != nil {
+				t.Fatalf("rateLimitClient.Do() error = %v", err)
+			}
+		}
+
+		if want := time.Second; slept != want {
+			t.Errorf("rateLimitClient.Do() slept = %v, want %v", slept, want)
+		}
+	})
+
+	t.Run("halves the rate on 429", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(newRes(http.StatusTooManyRequests), nil)
+		c := &rateLimitClient{httpClient: httpClient, maxRate: 4, burst: 1, rate: 4, tokens: 1}
+
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+		c.Do(req)
+
+		if want := 2.0; c.rate != want {
+			t.Errorf("rateLimitClient.rate = %v, want %v", c.rate, want)
+		}
+	})
+
+	t.Run("stops when the daily budget is exhausted", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(newRes(http.StatusOK), nil)
+		c := &rateLimitClient{httpClient: httpClient, dailyBudget: 2}
+
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+			if _, err := c.Do(req); err != nil {
+				t.Fatalf("rateLimitClient.Do() error = %v", err)
+			}
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+		if _, err := c.Do(req); err != ErrBudgetExhausted {
+			t.Errorf("rateLimitClient.Do() error = %v, want %v", err, ErrBudgetExhausted)
+		}
+
+		current = current.Add(24 * time.Hour)
+		if _, err := c.Do(req); err != nil {
+			t.Errorf("rateLimitClient.Do() on the next day error = %v", err)
+		}
+		httpClient.AssertNumberOfCalls(t, "Do", 3)
+	})
+
+	t.Run("shares the daily budget through the state file", func(t *testing.T) {
+		httpClient := &mockHttpClient{}
+		httpClient.On("Do", mock.Anything).Return(newRes(http.StatusOK), nil)
+		statePath := filepath.Join(t.TempDir(), "budget.json")
+
+		for i := 0; i < 2; i++ {
+			c := &rateLimitClient{httpClient: httpClient, dailyBudget: 2, statePath: statePath}
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+			if _, err := c.Do(req); err != nil {
+				t.Fatalf("rateLimitClient.Do() error = %v", err)
+			}
+		}
+
+		c := &rateLimitClient{httpClient: httpClient, dailyBudget: 2, statePath: statePath}
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/product/sample-slug", nil)
+		if _, err := c.Do(req); err != ErrBudgetExhausted {
+			t.Errorf("rateLimitClient.Do() in a new process error = %v, want %v", err, ErrBudgetExhausted)
+		}
+	})
+}
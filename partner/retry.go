@@ -0,0 +1,167 @@
+package partner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/andrysds/dropship-checker/backoff"
+)
+
+const (
+	retryMaxAttemptsEnvKey = "RETRY_MAX_ATTEMPTS"
+	retryBaseDelayEnvKey   = "RETRY_BASE_DELAY"
+	retryMaxDelayEnvKey    = "RETRY_MAX_DELAY"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+)
+
+// sleep is a variable so tests don't have to wait for real backoff delays.
+var sleep = backoff.Sleep
+
+// retryClient retries idempotent requests on network errors, 429 and 5xx
+// responses using exponential backoff with jitter.
+type retryClient struct {
+	httpClient  httpClient
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func newRetryClient(c httpClient) *retryClient {
+	return &retryClient{
+		httpClient:  c,
+		maxAttempts: envInt(retryMaxAttemptsEnvKey, defaultRetryMaxAttempts),
+		baseDelay:   envDuration(retryBaseDelayEnvKey, defaultRetryBaseDelay),
+		maxDelay:    envDuration(retryMaxDelayEnvKey, defaultRetryMaxDelay),
+	}
+}
+
+func (c *retryClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	counter := attemptCounterFrom(ctx)
+
+	for attempt := 1; ; attempt++ {
+		if counter != nil {
+			*counter = attempt
+		}
+
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		res, err := c.httpClient.Do(req)
+		if attempt >= c.maxAttempts || !isIdempotent(req.Method) || !shouldRetry(ctx, res, err) {
+			return res, err
+		}
+
+		delay := backoff.Delay(attempt, c.baseDelay, c.maxDelay)
+		if res != nil {
+			if d, ok := retryAfter(res); ok {
+				delay = d
+				if delay > c.maxDelay {
+					delay = c.maxDelay
+				}
+			}
+			drain(res)
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, ErrBudgetExhausted)
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests ||
+		(res.StatusCode >= 500 && res.StatusCode != http.StatusNotImplemented)
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+func drain(res *http.Response) {
+	if res.Body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+}
+
+type attemptCounterKey struct{}
+
+// WithAttemptCounter returns a context that makes the requests of a call
+// made with it record the number of attempts they needed into n.
+func WithAttemptCounter(ctx context.Context, n *int) context.Context {
+	return context.WithValue(ctx, attemptCounterKey{}, n)
+}
+
+func attemptCounterFrom(ctx context.Context) *int {
+	n, _ := ctx.Value(attemptCounterKey{}).(*int)
+	return n
+}
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
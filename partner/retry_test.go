@@ -0,0 +1,143 @@
+package partner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetryClient_Do(t *testing.T) {
+	var delays []time.Duration
+	origSleep := sleep
+	defer func() { sleep = origSleep }()
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	newRes := func(statusCode int, header http.Header) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewBuffer([]byte(""))),
+		}
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		httpClient     func() *mockHttpClient
+		wantStatusCode int
+		wantErr        bool
+		wantAttempts   int
+		wantDelays     []time.Duration
+	}{
+		{
+			name:   "no retry on success",
+			method: http.MethodGet,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				c.On("Do", mock.Anything).Return(newRes(http.StatusOK, http.Header{}), nil).Once()
+				return c
+			},
+			wantStatusCode: http.StatusOK,
+			wantAttempts:   1,
+		},
+		{
+			name:   "retry on 5xx then succeed",
+			method: http.MethodGet,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				c.On("Do", mock.Anything).Return(newRes(http.StatusBadGateway, http.Header{}), nil).Once()
+				c.On("Do", mock.Anything).Return(newRes(http.StatusOK, http.Header{}), nil).Once()
+				return c
+			},
+			wantStatusCode: http.StatusOK,
+			wantAttempts:   2,
+		},
+		{
+			name:   "retry on network error until max attempts",
+			method: http.MethodGet,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				c.On("Do", mock.Anything).Return(nil, fmt.Errorf("connection reset")).Times(3)
+				return c
+			},
+			wantErr:      true,
+			wantAttempts: 3,
+		},
+		{
+			name:   "honor Retry-After on 429",
+			method: http.MethodGet,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				header := http.Header{"Retry-After": []string{"2"}}
+				c.On("Do", mock.Anything).Return(newRes(http.StatusTooManyRequests, header), nil).Once()
+				c.On("Do", mock.Anything).Return(newRes(http.StatusOK, http.Header{}), nil).Once()
+				return c
+			},
+			wantStatusCode: http.StatusOK,
+			wantAttempts:   2,
+			wantDelays:     []time.Duration{2 * time.Second},
+		},
+		{
+			name:   "no retry on 4xx",
+			method: http.MethodGet,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				c.On("Do", mock.Anything).Return(newRes(http.StatusNotFound, http.Header{}), nil).Once()
+				return c
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantAttempts:   1,
+		},
+		{
+			name:   "no retry on non idempotent request",
+			method: http.MethodPost,
+			httpClient: func() *mockHttpClient {
+				c := &mockHttpClient{}
+				c.On("Do", mock.Anything).Return(newRes(http.StatusServiceUnavailable, http.Header{}), nil).Once()
+				return c
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantAttempts:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays = nil
+			httpClient := tt.httpClient()
+			c := &retryClient{
+				httpClient:  httpClient,
+				maxAttempts: 3,
+				baseDelay:   time.Millisecond,
+				maxDelay:    10 * time.Second,
+			}
+
+			var attempts int
+			req, _ := http.NewRequest(tt.method, "https://example.com/product/sample-slug", nil)
+			req = req.WithContext(WithAttemptCounter(req.Context(), &attempts))
+
+			res, err := c.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retryClient.Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && res.StatusCode != tt.wantStatusCode {
+				t.Errorf("retryClient.Do() status code = %v, want %v", res.StatusCode, tt.wantStatusCode)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("retryClient.Do() attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+			if tt.wantDelays != nil && fmt.Sprint(delays) != fmt.Sprint(tt.wantDelays) {
+				t.Errorf("retryClient.Do() delays = %v, want %v", delays, tt.wantDelays)
+			}
+			httpClient.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package partner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/product"
+)
+
+const (
+	sessionTTLEnvKey      = "SESSION_TTL"
+	productCacheTTLEnvKey = "PRODUCT_CACHE_TTL"
+)
+
+const defaultSessionTTL = 30 * time.Minute
+
+type contextPartner interface {
+	LoginContext(ctx context.Context) error
+	GetProductContext(ctx context.Context, slug string) (*product.Product, error)
+}
+
+type cachedProduct struct {
+	product   *product.Product
+	fetchedAt time.Time
+}
+
+// Session keeps a partner logged in across check runs of a long running
+// process. Logins within SESSION_TTL of the last one are skipped, and a
+// request the partner rejects as unauthorized logs in again once. Products
+// are cached for PRODUCT_CACHE_TTL, which is off by default.
+type Session struct {
+	partner  contextPartner
+	ttl      time.Duration
+	cacheTTL time.Duration
+
+	mu         sync.Mutex
+	loggedInAt time.Time
+	cache      map[string]cachedProduct
+	hits       int
+	misses     int
+}
+
+func NewSession(p contextPartner) *Session {
+	return &Session{
+		partner:  p,
+		ttl:      envDuration(sessionTTLEnvKey, defaultSessionTTL),
+		cacheTTL: envDuration(productCacheTTLEnvKey, 0),
+		cache:    map[string]cachedProduct{},
+	}
+}
+
+func (s *Session) Login() error {
+	return s.LoginContext(context.Background())
+}
+
+func (s *Session) LoginContext(ctx context.Context) error {
+	s.mu.Lock()
+	fresh := !s.loggedInAt.IsZero() && now().Sub(s.loggedInAt) < s.ttl
+	s.mu.Unlock()
+	if fresh {
+		return nil
+	}
+	return s.login(ctx)
+}
+
+func (s *Session) login(ctx context.Context) error {
+	if err := s.partner.LoginContext(ctx); err != nil {
+		s.mu.Lock()
+		s.loggedInAt = time.Time{}
+		s.mu.Unlock()
+		return err
+	}
+
+	s.mu.Lock()
+	s.loggedInAt = now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Session) GetProduct(slug string) (*product.Product, error) {
+	return s.GetProductContext(context.Background(), slug)
+}
+
+func (s *Session) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	if p, ok := s.cached(slug); ok {
+		return p, nil
+	}
+
+	p, err := s.partner.GetProductContext(ctx, slug)
+	if errors.Is(err, ErrUnauthorized) {
+		if err := s.login(ctx); err != nil {
+			return nil, err
+		}
+		p, err = s.partner.GetProductContext(ctx, slug)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cacheTTL > 0 {
+		s.mu.Lock()
+		s.cache[slug] = cachedProduct{product: p, fetchedAt: now()}
+		s.mu.Unlock()
+	}
+	return p, nil
+}
+
+func (s *Session) cached(slug string) (*product.Product, bool) {
+	if s.cacheTTL <= 0 {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.cache[slug]
+	if ok && now().Sub(c.fetchedAt) < s.cacheTTL {
+		s.hits++
+		return c.product, true
+	}
+	delete(s.cache, slug)
+	s.misses++
+	return nil, false
+}
+
+// CacheStats returns how many product lookups were served from the cache
+// and how many went to the partner, while the cache is on.
+func (s *Session) CacheStats() (hits, misses int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits, s.misses
+}
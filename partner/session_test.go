@@ -0,0 +1,110 @@
+package partner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// sessionPartner rejects requests while loggedIn is false, like a partner
+// whose token ran out.
+type sessionPartner struct {
+	logins   int
+	requests int
+	loggedIn bool
+}
+
+func (p *sessionPartner) LoginContext(ctx context.Context) error {
+	p.logins++
+	p.loggedIn = true
+	return nil
+}
+
+func (p *sessionPartner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	if !p.loggedIn {
+		return nil, ErrUnauthorized
+	}
+	p.requests++
+	return &product.Product{Name: slug}, nil
+}
+
+func TestSession(t *testing.T) {
+	origNow := now
+	defer func() { now = origNow }()
+	clock := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+
+	os.Setenv(productCacheTTLEnvKey, "5m")
+	defer os.Unsetenv(productCacheTTLEnvKey)
+
+	p := &sessionPartner{}
+	s := NewSession(p)
+
+	t.Run("login is kept", func(t *testing.T) {
+		s.Login()
+		clock = clock.Add(10 * time.Minute)
+		s.Login()
+		if p.logins != 1 {
+			t.Errorf("partner logins = %v, want 1", p.logins)
+		}
+
+		clock = clock.Add(defaultSessionTTL)
+		s.Login()
+		if p.logins != 2 {
+			t.Errorf("partner logins after SESSION_TTL = %v, want 2", p.logins)
+		}
+	})
+
+	t.Run("logs in again when unauthorized", func(t *testing.T) {
+		p.loggedIn = false
+		if _, err := s.GetProduct("sample-slug"); err != nil {
+			t.Fatalf("Session.GetProduct() error = %v", err)
+		}
+		if p.logins != 3 {
+			t.Errorf("partner logins = %v, want 3", p.logins)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		requests := p.requests
+		s.GetProduct("sample-slug")
+		clock = clock.Add(5 * time.Minute)
+		s.GetProduct("sample-slug")
+
+		if got := p.requests - requests; got != 1 {
+			t.Errorf("partner requests = %v, want 1", got)
+		}
+		if hits, misses := s.CacheStats(); hits != 1 || misses != 2 {
+			t.Errorf("Session.CacheStats() = %v, %v, want 1, 2", hits, misses)
+		}
+	})
+}
+
+func TestSession_loginError(t *testing.T) {
+	p := &failingLoginPartner{}
+	s := NewSession(p)
+
+	if err := s.Login(); err == nil {
+		t.Fatalf("Session.Login() error = nil")
+	}
+	p.ok = true
+	if err := s.Login(); err != nil {
+		t.Fatalf("Session.Login() error = %v", err)
+	}
+}
+
+type failingLoginPartner struct {
+	sessionPartner
+	ok bool
+}
+
+func (p *failingLoginPartner) LoginContext(ctx context.Context) error {
+	if !p.ok {
+		return errors.New("sample error")
+	}
+	return p.sessionPartner.LoginContext(ctx)
+}
@@ -0,0 +1,116 @@
+package partnertest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// Factory returns a fresh Partner serving products, with valid credentials.
+type Factory func(t *testing.T, products map[string]*product.Product) checker.ContextPartner
+
+// Products are the products the conformance suite seeds partners with.
+var Products = map[string]*product.Product{
+	"sample-slug": {
+		Name:        "sample name",
+		Description: "sample description",
+		Variants: []product.Variant{
+			{Name: "red", Price: 10000, Stock: 3},
+			{Name: "blue", Price: 12000, Stock: 50},
+		},
+	},
+	"other-slug": {
+		Name:        "other name",
+		Description: "other description",
+		Variants: []product.Variant{
+			{Name: "default", Price: 25000, Stock: 10},
+		},
+	},
+}
+
+// RunConformance checks that the partners newPartner returns behave the way
+// Checker relies on.
+func RunConformance(t *testing.T, newPartner Factory) {
+	t.Run("get product after login", func(t *testing.T) {
+		p := newPartner(t, Products)
+		if err := p.Login(); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+
+		for slug, want := range Products {
+			got, err := p.GetProduct(slug)
+			if err != nil {
+				t.Errorf("GetProduct(%q) error = %v", slug, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetProduct(%q) = %v, want %v", slug, got, want)
+			}
+		}
+	})
+
+	t.Run("get product before login", func(t *testing.T) {
+		p := newPartner(t, Products)
+
+		if _, err := p.GetProduct("sample-slug"); !errors.Is(err, partner.ErrUnauthorized) {
+			t.Errorf("GetProduct() error = %v, want %v", err, partner.ErrUnauthorized)
+		}
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		p := newPartner(t, Products)
+		if err := p.Login(); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+
+		if _, err := p.GetProduct("missing-slug"); !errors.Is(err, partner.ErrProductNotFound) {
+			t.Errorf("GetProduct() error = %v, want %v", err, partner.ErrProductNotFound)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		p := newPartner(t, Products)
+		if err := p.Login(); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := p.GetProductContext(ctx, "sample-slug"); !errors.Is(err, context.Canceled) {
+			t.Errorf("GetProductContext() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("concurrent get product", func(t *testing.T) {
+		p := newPartner(t, Products)
+		if err := p.Login(); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for slug, want := range Products {
+					got, err := p.GetProduct(slug)
+					if err != nil {
+						t.Errorf("GetProduct(%q) error = %v", slug, err)
+						continue
+					}
+					if got.Name != want.Name {
+						t.Errorf("GetProduct(%q).Name = %v, want %v", slug, got.Name, want.Name)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,93 @@
+// Package partnertest provides an in-memory Partner and a conformance suite
+// every checker.Partner implementation can run.
+package partnertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// Partner is an in-memory checker.ContextPartner. It behaves like the HTTP
+// partner: products can only be fetched after Login and unknown slugs fail
+// with partner.ErrProductNotFound.
+type Partner struct {
+	mu       sync.Mutex
+	products map[string]*product.Product
+	loggedIn bool
+	requests map[string]int
+}
+
+func NewPartner(products map[string]*product.Product) *Partner {
+	p := &Partner{
+		products: map[string]*product.Product{},
+		requests: map[string]int{},
+	}
+	for slug, prod := range products {
+		p.Add(slug, prod)
+	}
+	return p
+}
+
+// Add stores prod under slug, replacing what was there.
+func (p *Partner) Add(slug string, prod *product.Product) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.products[slug] = prod.Copy()
+}
+
+// Remove makes slug unknown, like a discontinued product.
+func (p *Partner) Remove(slug string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.products, slug)
+}
+
+// Requests returns how many times slug was fetched.
+func (p *Partner) Requests(slug string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests[slug]
+}
+
+func (p *Partner) Login() error {
+	return p.LoginContext(context.Background())
+}
+
+func (p *Partner) LoginContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loggedIn = true
+	return nil
+}
+
+func (p *Partner) GetProduct(slug string) (*product.Product, error) {
+	return p.GetProductContext(context.Background(), slug)
+}
+
+func (p *Partner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.loggedIn {
+		return nil, partner.ErrUnauthorized
+	}
+
+	p.requests[slug]++
+	prod, ok := p.products[slug]
+	if !ok {
+		return nil, partner.ErrProductNotFound
+	}
+
+	return prod.Copy(), nil
+}
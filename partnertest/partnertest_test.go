@@ -0,0 +1,30 @@
+package partnertest
+
+import (
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestPartner(t *testing.T) {
+	RunConformance(t, func(t *testing.T, products map[string]*product.Product) checker.ContextPartner {
+		return NewPartner(products)
+	})
+}
+
+func TestPartner_copiesProducts(t *testing.T) {
+	p := NewPartner(Products)
+	p.Login()
+
+	got, _ := p.GetProduct("sample-slug")
+	got.Variants[0].Price = 0
+
+	again, _ := p.GetProduct("sample-slug")
+	if again.Variants[0].Price == 0 {
+		t.Errorf("GetProduct() returned a product sharing state with the store")
+	}
+	if n := p.Requests("sample-slug"); n != 2 {
+		t.Errorf("Requests() = %v, want %v", n, 2)
+	}
+}
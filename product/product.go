@@ -6,6 +6,13 @@ type Product struct {
 	Variants    []Variant `json:"variants"`
 }
 
+// Copy returns a copy of p that shares nothing with it.
+func (p *Product) Copy() *Product {
+	res := *p
+	res.Variants = append([]Variant(nil), p.Variants...)
+	return &res
+}
+
 func (p *Product) VariantMap() map[string]Variant {
 	res := map[string]Variant{}
 	for _, v := range p.Variants {
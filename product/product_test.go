@@ -70,7 +70,7 @@ func TestVariant_IsStockLevelChange(t *testing.T) {
 		},
 		{
 			name:          "stock level is changed",
-			currentStock:  1,
+			currentStock:  5,
 			oldStockLevel: 0,
 			want:          true,
 		},
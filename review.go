@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/review"
+)
+
+const (
+	ignoreListPathEnvKey  = "IGNORE_LIST_PATH"
+	defaultIgnoreListPath = "ignore.csv"
+)
+
+func runReview(ctx context.Context, args []string) {
+	ignorePath := os.Getenv(ignoreListPathEnvKey)
+	if ignorePath == "" {
+		ignorePath = defaultIgnoreListPath
+	}
+
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	reportPath := fs.String("report", "", "review the findings of this JSON report instead of checking now")
+	out := fs.String("o", reviewedPath(os.Getenv(csvPathEnvKey)), "write the CSV with the accepted changes to this path")
+	fs.StringVar(&ignorePath, "ignore", ignorePath, "add rejected changes to this ignore list, and skip the ones on it")
+	fs.Parse(args)
+
+	records := loadRecords(ctx)
+
+	report, err := reviewReport(ctx, records, *reportPath)
+	if err != nil {
+		log.Fatalln("[ERROR] [reading report]", err)
+	}
+
+	ignored, err := review.LoadIgnoreList(ignorePath)
+	if err != nil {
+		log.Fatalln("[ERROR] [loading ignore list]", err)
+	}
+
+	findings := review.Reviewable(report.Findings, ignored)
+	if len(findings) == 0 {
+		log.Println("[INFO] nothing to review")
+		return
+	}
+
+	decisions, err := review.NewSession(os.Stdin, os.Stdout).Review(ctx, findings)
+	if err != nil {
+		log.Fatalln("[ERROR] [review] stopped, nothing written:", err)
+	}
+
+	updated, accepted := review.Apply(records, decisions)
+	if accepted > 0 {
+		if err := writeCSV(*out, updated); err != nil {
+			log.Fatalln("[ERROR] [writing csv]", err)
+		}
+		log.Printf("[INFO] accepted changes written; path: %s\n", *out)
+	}
+
+	rejected, err := review.AppendIgnored(ignorePath, decisions, time.Now())
+	if err != nil {
+		log.Fatalln("[ERROR] [writing ignore list]", err)
+	}
+
+	log.Printf("reviewed: %d; accepted: %d; rejected: %d; skipped: %d\n",
+		len(decisions), accepted, rejected, len(decisions)-accepted-rejected)
+}
+
+// reviewReport reads the report at path, or checks records against the
+// partner when there is none. The readings of a check that look like partner
+// glitches are quarantined, so they aren't offered; a saved report was
+// quarantined when it was written.
+func reviewReport(ctx context.Context, records []csv.Record, path string) (*checker.Report, error) {
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		report := &checker.Report{}
+		return report, json.Unmarshal(b, report)
+	}
+
+	runCtx, cancel := withRunTimeout(ctx)
+	defer cancel()
+
+	p := newPartner(ctx)
+	defer closePartner(p)
+
+	report, err := checker.NewChecker(records, p).CheckContext(runCtx)
+	if err != nil {
+		log.Println("[ERROR] [Check]", err)
+	}
+
+	quarantine(optionalHistoryStore(), report)
+	return report, nil
+}
+
+// reviewedPath returns path with ".reviewed" before its extension.
+func reviewedPath(path string) string {
+	if path == "" {
+		return "reviewed.csv"
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".reviewed" + ext
+}
+
+func writeCSV(path string, records []csv.Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := csv.Write(f, records); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
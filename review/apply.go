@@ -0,0 +1,85 @@
+package review
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+)
+
+const (
+	priceKeyEnvKey      = "PRICE_KEY"
+	stockLevelKeyEnvKey = "STOCK_LEVEL_KEY"
+	skuKeyEnvKey        = "SKU_KEY"
+)
+
+// Apply returns records with the accepted decisions written into their rows,
+// and how many were written. records are left as they are. A decision whose
+// row doesn't hold its SKU anymore is logged and left out.
+func Apply(records []csv.Record, decisions []Decision) ([]csv.Record, int) {
+	priceKey := os.Getenv(priceKeyEnvKey)
+	stockLevelKey := os.Getenv(stockLevelKeyEnvKey)
+	skuKey := os.Getenv(skuKeyEnvKey)
+
+	res := make([]csv.Record, len(records))
+	copy(res, records)
+
+	applied := 0
+	for _, d := range decisions {
+		if d.Action != Accept {
+			continue
+		}
+
+		f := d.Finding
+		i := f.Row - 1
+		if i < 0 || i >= len(res) || (skuKey != "" && res[i].Data[skuKey] != f.SKU) {
+			log.Printf("[WARN] row doesn't match the finding; row: %d; sku: %s\n", f.Row, f.SKU)
+			continue
+		}
+
+		data := make(map[string]string, len(res[i].Data))
+		for k, v := range res[i].Data {
+			data[k] = v
+		}
+		switch f.Kind {
+		case checker.KindPriceChanged:
+			data[priceKey] = formatPrice(data[priceKey], d.Value)
+		case checker.KindStockLevelChanged:
+			data[stockLevelKey] = strconv.Itoa(d.Value)
+		default:
+			continue
+		}
+		res[i] = csv.Record{Data: data}
+		applied++
+	}
+	return res, applied
+}
+
+// formatPrice formats price the way old is written, with its "Rp" prefix
+// and thousands separators when it has them.
+func formatPrice(old string, price int) string {
+	s := strconv.Itoa(price)
+	if strings.Contains(old, ",") {
+		s = formatThousands(price)
+	}
+	if strings.HasPrefix(strings.TrimSpace(old), "Rp") {
+		s = "Rp" + s
+	}
+	return s
+}
+
+func formatThousands(v int) string {
+	s := strconv.Itoa(v)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package review
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestApply(t *testing.T) {
+	os.Setenv(priceKeyEnvKey, "Price")
+	os.Setenv(stockLevelKeyEnvKey, "Stock Level")
+	os.Setenv(skuKeyEnvKey, "SKU")
+	defer os.Unsetenv(priceKeyEnvKey)
+	defer os.Unsetenv(stockLevelKeyEnvKey)
+	defer os.Unsetenv(skuKeyEnvKey)
+
+	records := []csv.Record{
+		{Data: map[string]string{"SKU": "SKU-1", "Price": "Rp10,000", "Stock Level": "2"}},
+		{Data: map[string]string{"SKU": "SKU-2", "Price": "5000", "Stock Level": "1"}},
+		{Data: map[string]string{"SKU": "SKU-3", "Price": "7000", "Stock Level": "1"}},
+	}
+	decisions := []Decision{
+		{Finding: sampleFindings[0], Action: Accept, Value: 12000},
+		{Finding: sampleFindings[1], Action: Accept, Value: product.LowStock},
+		{Finding: sampleFindings[2], Action: Reject, Value: 4000},
+		{Finding: checker.Finding{Kind: checker.KindPriceChanged, Row: 3, SKU: "SKU-moved"}, Action: Accept, Value: 1},
+	}
+
+	got, applied := Apply(records, decisions)
+	if applied != 2 {
+		t.Errorf("Apply() applied = %v, want 2", applied)
+	}
+
+	want := []csv.Record{
+		{Data: map[string]string{"SKU": "SKU-1", "Price": "Rp12,000", "Stock Level": "1"}},
+		records[1],
+		records[2],
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+	if records[0].Data["Price"] != "Rp10,000" {
+		t.Errorf("Apply() changed the records it was given")
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		old   string
+		price int
+		want  string
+	}{
+		{old: "10000", price: 1250000, want: "1250000"},
+		{old: "10,000", price: 1250000, want: "1,250,000"},
+		{old: "Rp10,000", price: 950, want: "Rp950"},
+		{old: "Rp10000", price: 12000, want: "Rp12000"},
+	}
+	for _, tt := range tests {
+		if got := formatPrice(tt.old, tt.price); got != tt.want {
+			t.Errorf("formatPrice(%q, %v) = %q, want %q", tt.old, tt.price, got, tt.want)
+		}
+	}
+}
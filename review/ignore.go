@@ -0,0 +1,106 @@
+package review
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+)
+
+var ignoreHeaders = []string{"Partner", "SKU", "Slug", "Variant", "Kind", "Old Value", "New Value", "Rejected At"}
+
+// IgnoreList holds the rejected changes, so they aren't offered again while
+// the row and the partner still disagree the same way.
+type IgnoreList map[string]bool
+
+func ignoreKey(partner, sku, slug, variant, kind, oldValue, newValue string) string {
+	return strings.Join([]string{partner, sku, slug, variant, kind, oldValue, newValue}, "|")
+}
+
+func findingKey(f checker.Finding) string {
+	return ignoreKey(f.Partner, f.SKU, f.Slug, f.Variant, string(f.Kind), strconv.Itoa(f.OldValue), strconv.Itoa(f.NewValue))
+}
+
+func (l IgnoreList) Has(f checker.Finding) bool {
+	return l[findingKey(f)]
+}
+
+// LoadIgnoreList reads the ignore list CSV at path. A missing file is an
+// empty list.
+func LoadIgnoreList(path string) (IgnoreList, error) {
+	l := IgnoreList{}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(ignoreHeaders)
+	if _, err := r.Read(); err == io.EOF {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l[ignoreKey(row[0], row[1], row[2], row[3], row[4], row[5], row[6])] = true
+	}
+}
+
+// AppendIgnored adds the rejected decisions to the ignore list CSV at path,
+// creating it when it doesn't exist, and returns how many were added.
+// Without rejected decisions the file is left alone.
+func AppendIgnored(path string, decisions []Decision, at time.Time) (int, error) {
+	var rejected []checker.Finding
+	for _, d := range decisions {
+		if d.Action == Reject {
+			rejected = append(rejected, d.Finding)
+		}
+	}
+	if len(rejected) == 0 {
+		return 0, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(ignoreHeaders)
+	}
+
+	for _, g := range rejected {
+		w.Write([]string{g.Partner, g.SKU, g.Slug, g.Variant, string(g.Kind),
+			strconv.Itoa(g.OldValue), strconv.Itoa(g.NewValue), at.Format(time.RFC3339)})
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return len(rejected), f.Close()
+}
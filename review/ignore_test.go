@@ -0,0 +1,41 @@
+package review
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIgnoreList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ignore.csv")
+
+	l, err := LoadIgnoreList(path)
+	if err != nil || len(l) != 0 {
+		t.Fatalf("LoadIgnoreList() of a missing file = %v, %v, want an empty list", l, err)
+	}
+
+	at := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, d := range [][]Decision{
+		{{Finding: sampleFindings[0], Action: Reject}, {Finding: sampleFindings[1], Action: Accept}},
+		{{Finding: sampleFindings[2], Action: Reject}, {Finding: sampleFindings[3]}},
+	} {
+		if n, err := AppendIgnored(path, d, at); err != nil || n != 1 {
+			t.Fatalf("AppendIgnored() = %v, %v, want 1", n, err)
+		}
+	}
+
+	if l, err = LoadIgnoreList(path); err != nil {
+		t.Fatalf("LoadIgnoreList() error = %v", err)
+	}
+	for i, want := range []bool{true, false, true, false} {
+		if got := l.Has(sampleFindings[i]); got != want {
+			t.Errorf("IgnoreList.Has(finding %d) = %v, want %v", i, got, want)
+		}
+	}
+
+	changed := sampleFindings[0]
+	changed.NewValue = 13000
+	if l.Has(changed) {
+		t.Errorf("IgnoreList.Has() = true for a new price of an ignored change")
+	}
+}
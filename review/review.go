@@ -0,0 +1,289 @@
+// Package review walks a person through the price and stock level changes of
+// a check run, so they are accepted, rejected or edited before they reach the
+// CSV.
+package review
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// Action is what the reviewer decided about a finding.
+type Action int
+
+const (
+	Skip Action = iota
+	Accept
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Accept:
+		return "accepted"
+	case Reject:
+		return "rejected"
+	}
+	return "skipped"
+}
+
+// Decision is the action taken on a finding. Value is what accepted
+// findings write to the CSV: the partner's new value, unless it was edited.
+type Decision struct {
+	Finding checker.Finding
+	Action  Action
+	Value   int
+}
+
+// Reviewable returns the price and stock level changes of findings, leaving
+// out the ones on the ignore list. Rows with a needs-review finding are left
+// out too, even in reports saved before their changes were quarantined:
+// there is no change to accept until a person has looked at the partner.
+func Reviewable(findings []checker.Finding, ignored IgnoreList) []checker.Finding {
+	quarantined := map[int]bool{}
+	for _, f := range findings {
+		if f.Kind == checker.KindNeedsReview {
+			quarantined[f.Row] = true
+		}
+	}
+
+	var res []checker.Finding
+	for _, f := range findings {
+		if (f.Kind == checker.KindPriceChanged || f.Kind == checker.KindStockLevelChanged) && !quarantined[f.Row] && !ignored.Has(f) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+const help = `  a  accept the change
+  r  reject it and add it to the ignore list
+  e  edit the value to write, then accept
+  s  skip it, leaving the row as it is
+  A  accept this and the rest of this partner's changes
+  R  reject this and the rest of this partner's changes
+  q  stop reviewing; the rest are skipped
+`
+
+// Session reads the decisions of a reviewer from in and prompts on out.
+type Session struct {
+	lines <-chan string
+	out   io.Writer
+}
+
+// NewSession starts reading the lines of in.
+func NewSession(in io.Reader, out io.Writer) *Session {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+	return &Session{lines: lines, out: out}
+}
+
+// Review asks for a decision on every finding, in order, and returns them.
+// The end of the input stops the review like q does. When ctx is done the
+// decisions so far are returned with its error.
+func (s *Session) Review(ctx context.Context, findings []checker.Finding) ([]Decision, error) {
+	decisions := make([]Decision, len(findings))
+	for i, f := range findings {
+		decisions[i] = Decision{Finding: f, Value: f.NewValue}
+	}
+
+	for i := range decisions {
+		if decisions[i].Action != Skip {
+			continue // decided in bulk
+		}
+
+		f := decisions[i].Finding
+		fmt.Fprintf(s.out, "\n[%d/%d] %s\n  %s\n", i+1, len(decisions), describe(f), change(f))
+		if quit, err := s.ask(ctx, decisions, i); quit || err != nil {
+			return decisions, err
+		}
+	}
+	return decisions, nil
+}
+
+// ask prompts until it gets a decision on decisions[i]. Bulk answers decide
+// the rest of the partner's findings too.
+func (s *Session) ask(ctx context.Context, decisions []Decision, i int) (quit bool, err error) {
+	d := &decisions[i]
+	for {
+		fmt.Fprint(s.out, "accept (a), reject (r), edit (e), skip (s), all of partner (A/R), quit (q), help (?): ")
+		line, err := s.readLine(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		switch line {
+		case "a":
+			d.Action = Accept
+			return false, nil
+		case "r":
+			d.Action = Reject
+			return false, nil
+		case "e":
+			v, ok, err := s.edit(ctx, d.Finding)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				d.Action, d.Value = Accept, v
+				return false, nil
+			}
+		case "s":
+			return false, nil
+		case "A", "R":
+			action := Accept
+			if line == "R" {
+				action = Reject
+			}
+			n := 0
+			for j := i; j < len(decisions); j++ {
+				if decisions[j].Finding.Partner == d.Finding.Partner && decisions[j].Action == Skip {
+					decisions[j].Action = action
+					n++
+				}
+			}
+			fmt.Fprintf(s.out, "  %s %d changes of %s\n", action, n, partnerName(d.Finding.Partner))
+			return false, nil
+		case "q", eof:
+			return true, nil
+		case "?":
+			fmt.Fprint(s.out, help)
+		default:
+			fmt.Fprintf(s.out, "  unknown answer %q; ? for help\n", line)
+		}
+	}
+}
+
+// eof is the line readLine returns at the end of the input. No answer is
+// empty, so it can't be mistaken for one.
+const eof = ""
+
+func (s *Session) readLine(ctx context.Context) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case line, ok := <-s.lines:
+			if !ok {
+				fmt.Fprintln(s.out)
+				return eof, nil
+			}
+			if line != "" {
+				return line, nil
+			}
+		}
+	}
+}
+
+// edit asks for the value to write instead of the partner's. An empty
+// answer goes back to the prompt.
+func (s *Session) edit(ctx context.Context, f checker.Finding) (int, bool, error) {
+	for {
+		if f.Kind == checker.KindStockLevelChanged {
+			fmt.Fprint(s.out, "  stock level (out, low, high or 0-2; empty to go back): ")
+		} else {
+			fmt.Fprint(s.out, "  price (empty to go back): ")
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		case line, ok := <-s.lines:
+			if !ok || line == "" {
+				return 0, false, nil
+			}
+			if v, err := parseValue(f.Kind, line); err == nil {
+				return v, true, nil
+			}
+			fmt.Fprintf(s.out, "  invalid value %q\n", line)
+		}
+	}
+}
+
+func parseValue(kind checker.Kind, s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if kind == checker.KindStockLevelChanged {
+		for level := product.OutOfStock; level <= product.HighStock; level++ {
+			if s == stockLevelName(level) || s == strconv.Itoa(level) {
+				return level, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown stock level %q", s)
+	}
+
+	return parsePrice(s)
+}
+
+// pricePattern matches whole prices, with or without thousands separators.
+var pricePattern = regexp.MustCompile(`^-?(\d+|\d{1,3}([.,]\d{3})+)$`)
+
+// parsePrice reads a whole price like "Rp 10.000" or "12,000". Commas and
+// dots may only separate thousands, so decimals like "12,50" are rejected.
+func parsePrice(s string) (int, error) {
+	digits := strings.TrimSpace(strings.TrimPrefix(s, "Rp"))
+	if !pricePattern.MatchString(digits) {
+		return 0, fmt.Errorf("%q is not a whole price", s)
+	}
+
+	v, err := strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(digits))
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("negative price %d", v)
+	}
+	return v, nil
+}
+
+func describe(f checker.Finding) string {
+	s := fmt.Sprintf("%s · row %d · %s · %s", partnerName(f.Partner), f.Row, f.SKU, f.Slug)
+	if f.Variant != "" {
+		s += " / " + f.Variant
+	}
+	return s
+}
+
+func change(f checker.Finding) string {
+	if f.Kind == checker.KindStockLevelChanged {
+		return fmt.Sprintf("stock level: %s → %s", stockLevelName(f.OldValue), stockLevelName(f.NewValue))
+	}
+
+	s := fmt.Sprintf("price: %s → %s", formatThousands(f.OldValue), formatThousands(f.NewValue))
+	if f.OldValue != 0 {
+		s += fmt.Sprintf(" (%+.1f%%)", float64(f.NewValue-f.OldValue)/float64(f.OldValue)*100)
+	}
+	return s
+}
+
+func partnerName(p string) string {
+	if p == "" {
+		return "the partner"
+	}
+	return p
+}
+
+func stockLevelName(level int) string {
+	switch level {
+	case product.OutOfStock:
+		return "out"
+	case product.LowStock:
+		return "low"
+	case product.HighStock:
+		return "high"
+	}
+	return strconv.Itoa(level)
+}
@@ -0,0 +1,161 @@
+package review
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+var sampleFindings = []checker.Finding{
+	{Kind: checker.KindPriceChanged, Partner: "acme", Row: 1, SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: 10000, NewValue: 11500},
+	{Kind: checker.KindStockLevelChanged, Partner: "acme", Row: 1, SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: product.HighStock, NewValue: product.LowStock},
+	{Kind: checker.KindPriceChanged, Partner: "other", Row: 2, SKU: "SKU-2", Slug: "old-hat", OldValue: 5000, NewValue: 4000},
+	{Kind: checker.KindPriceChanged, Partner: "other", Row: 3, SKU: "SKU-3", Slug: "cap", OldValue: 7000, NewValue: 7500},
+}
+
+func actions(decisions []Decision) []Action {
+	res := make([]Action, len(decisions))
+	for i, d := range decisions {
+		res[i] = d.Action
+	}
+	return res
+}
+
+func TestSession_Review(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantActions []Action
+		wantValues  []int
+	}{
+		{
+			name:        "one by one",
+			input:       "a\nr\ns\nx\n?\na\n",
+			wantActions: []Action{Accept, Reject, Skip, Accept},
+			wantValues:  []int{11500, product.LowStock, 4000, 7500},
+		},
+		{
+			name:        "edit",
+			input:       "e\nsample\nRp12,000\ne\n\ne\nout\n",
+			wantActions: []Action{Accept, Accept, Skip, Skip},
+			wantValues:  []int{12000, product.OutOfStock, 4000, 7500},
+		},
+		{
+			name:        "bulk per partner",
+			input:       "s\nA\n",
+			wantActions: []Action{Skip, Accept, Skip, Skip},
+			wantValues:  []int{11500, product.LowStock, 4000, 7500},
+		},
+		{
+			name:        "bulk reject, then next partner",
+			input:       "R\na\nR\n",
+			wantActions: []Action{Reject, Reject, Accept, Reject},
+			wantValues:  []int{11500, product.LowStock, 4000, 7500},
+		},
+		{
+			name:        "quit",
+			input:       "a\nq\na\n",
+			wantActions: []Action{Accept, Skip, Skip, Skip},
+			wantValues:  []int{11500, product.LowStock, 4000, 7500},
+		},
+		{
+			name:        "end of input",
+			input:       "a\n",
+			wantActions: []Action{Accept, Skip, Skip, Skip},
+			wantValues:  []int{11500, product.LowStock, 4000, 7500},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(strings.NewReader(tt.input), ioutil.Discard)
+			decisions, err := s.Review(context.Background(), sampleFindings)
+			if err != nil {
+				t.Fatalf("Session.Review() error = %v", err)
+			}
+
+			if got := actions(decisions); !reflect.DeepEqual(got, tt.wantActions) {
+				t.Errorf("Session.Review() actions = %v, want %v", got, tt.wantActions)
+			}
+			for i, d := range decisions {
+				if d.Value != tt.wantValues[i] {
+					t.Errorf("Session.Review() value %d = %v, want %v", i, d.Value, tt.wantValues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSession_Review_canceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	in, w := io.Pipe()
+	defer w.Close()
+	go w.Write([]byte("a\n"))
+
+	decisions, err := NewSession(in, ioutil.Discard).Review(ctx, sampleFindings)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Session.Review() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if decisions[0].Action != Accept {
+		t.Errorf("Session.Review() first action = %v, want %v", decisions[0].Action, Accept)
+	}
+}
+
+func TestSession_Review_prompt(t *testing.T) {
+	var out strings.Builder
+	NewSession(strings.NewReader("q\n"), &out).Review(context.Background(), sampleFindings)
+
+	want := "[1/4] acme · row 1 · SKU-1 · red-shirt / L\n  price: 10,000 → 11,500 (+15.0%)\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("Session.Review() output = %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "12000", want: 12000},
+		{input: "Rp12,000", want: 12000},
+		{input: "Rp 10.000", want: 10000},
+		{input: " 1.250.000 ", want: 1250000},
+		{input: "12,50", wantErr: true},
+		{input: "10.000,00", wantErr: true},
+		{input: "-5000", wantErr: true},
+		{input: "sample", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseValue(checker.KindPriceChanged, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseValue(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseValue(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReviewable(t *testing.T) {
+	findings := append([]checker.Finding{
+		{Kind: checker.KindNeedsReview, Partner: "acme", Row: 4, SKU: "SKU-4", NewValue: 990000},
+		{Kind: checker.KindPriceChanged, Partner: "acme", Row: 4, SKU: "SKU-4", OldValue: 9900, NewValue: 990000},
+		{Kind: checker.KindDiscontinued, Partner: "acme", Row: 5, SKU: "SKU-5"},
+	}, sampleFindings...)
+	ignored := IgnoreList{findingKey(sampleFindings[2]): true}
+
+	want := []checker.Finding{sampleFindings[0], sampleFindings[1], sampleFindings[3]}
+	if got := Reviewable(findings, ignored); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reviewable() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,558 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed rule condition.
+type Expr interface {
+	Eval(env *Env) (interface{}, error)
+}
+
+// Env is what an expression can see: named values, numbers, strings or
+// bools, the CSV columns of the row for col(), and the kinds of the row's
+// findings for has().
+type Env struct {
+	Vars    map[string]interface{}
+	Columns map[string]string
+	Kinds   map[string]bool
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits s into tokens. Strings are double quoted, with Go escapes.
+func lex(s string) ([]token, error) {
+	var res []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			res = append(res, token{tokNumber, s[i:j], i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			res = append(res, token{tokIdent, s[i:j], i})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string at %d: %w", i, err)
+			}
+			res = append(res, token{tokString, text, i})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			res = append(res, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(res, token{tokEOF, "", len(s)}), nil
+}
+
+// Parse parses a condition like
+//
+//	new_price > old_price * 1.1 && new_stock_level == low_stock
+//
+// It has numbers, double quoted strings, true and false, the arithmetic
+// operators, comparisons, && (and), || (or), ! (not), parentheses and calls
+// of the functions in funcs.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token when it is one of ops, which may be
+// operators or keywords.
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.i++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) or() (Expr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return l, nil
+		}
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "||", l: l, r: r}
+	}
+}
+
+func (p *parser) and() (Expr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return l, nil
+		}
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "&&", l: l, r: r}
+	}
+}
+
+func (p *parser) not() (Expr, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "!", e: e}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	l, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return l, nil
+	}
+	r, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) sum() (Expr, error) {
+	l, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) product() (Expr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return l, nil
+		}
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	if _, ok := p.accept("-"); ok {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "-", e: e}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at %d", t.text, t.pos)
+		}
+		return literal{v}, nil
+	case tokString:
+		return literal{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		if _, ok := p.accept("("); !ok {
+			return variable(t.text), nil
+		}
+		return p.call(t)
+	case tokOp:
+		if t.text == "(" {
+			e, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return e, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end at %d", t.pos)
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) call(name token) (Expr, error) {
+	f, ok := funcs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at %d", name.text, name.pos)
+	}
+
+	c := &call{name: name.text, f: f.f}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			c.args = append(c.args, arg)
+
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			if _, ok := p.accept(","); !ok {
+				return nil, fmt.Errorf("expected , or ) at %d", p.peek().pos)
+			}
+		}
+	}
+
+	if len(c.args) != f.args {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name.text, f.args, len(c.args))
+	}
+	return c, nil
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (l literal) Eval(env *Env) (interface{}, error) {
+	return l.v, nil
+}
+
+type variable string
+
+func (v variable) Eval(env *Env) (interface{}, error) {
+	val, ok := env.Vars[string(v)]
+	if !ok {
+		return nil, fmt.Errorf("unknown name %s", string(v))
+	}
+	return val, nil
+}
+
+type unary struct {
+	op string
+	e  Expr
+}
+
+func (u *unary) Eval(env *Env) (interface{}, error) {
+	v, err := u.e.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.op == "!" {
+		b, err := toBool(v)
+		return !b, err
+	}
+	n, err := toNumber(v)
+	return -n, err
+}
+
+type binary struct {
+	op   string
+	l, r Expr
+}
+
+func (b *binary) Eval(env *Env) (interface{}, error) {
+	l, err := b.l.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || don't evaluate their right side when the left one decides.
+	if b.op == "&&" || b.op == "||" {
+		lb, err := toBool(l)
+		if err != nil {
+			return nil, err
+		}
+		if lb == (b.op == "||") {
+			return lb, nil
+		}
+		r, err := b.r.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(r)
+	}
+
+	r, err := b.r.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.op == "==" || b.op == "!=" {
+		eq := equal(l, r)
+		return eq == (b.op == "=="), nil
+	}
+
+	if b.op == "+" {
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if lok && rok {
+			return ls + rs, nil
+		}
+	}
+
+	ln, err := toNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := toNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "<":
+		return ln < rn, nil
+	case "<=":
+		return ln <= rn, nil
+	case ">":
+		return ln > rn, nil
+	case ">=":
+		return ln >= rn, nil
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		if rn == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return ln / rn, nil
+	case "%":
+		if rn == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(ln, rn), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", b.op)
+}
+
+type call struct {
+	name string
+	f    func(env *Env, args []interface{}) (interface{}, error)
+	args []Expr
+}
+
+func (c *call) Eval(env *Env) (interface{}, error) {
+	args := make([]interface{}, len(c.args))
+	for i, a := range c.args {
+		v, err := a.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	v, err := c.f(env, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.name, err)
+	}
+	return v, nil
+}
+
+var funcs = map[string]struct {
+	args int
+	f    func(env *Env, args []interface{}) (interface{}, error)
+}{
+	// col returns the value of a CSV column of the row, "" when there is
+	// no such column.
+	"col": {1, func(env *Env, args []interface{}) (interface{}, error) {
+		return env.Columns[toString(args[0])], nil
+	}},
+	// has tells whether the row has a finding of a kind, like
+	// has("product_changed").
+	"has": {1, func(env *Env, args []interface{}) (interface{}, error) {
+		return env.Kinds[toString(args[0])], nil
+	}},
+	"num": {1, func(env *Env, args []interface{}) (interface{}, error) {
+		return toNumber(args[0])
+	}},
+	"contains": {2, func(env *Env, args []interface{}) (interface{}, error) {
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	}},
+	"lower": {1, func(env *Env, args []interface{}) (interface{}, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"abs": {1, func(env *Env, args []interface{}) (interface{}, error) {
+		n, err := toNumber(args[0])
+		return math.Abs(n), err
+	}},
+}
+
+// toNumber reads strings the way the checker reads CSV prices, so "Rp10,000"
+// is 10000.
+func toNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(v), "Rp", ""), ",", "")
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a number: %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
+
+func toBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("not a bool: %v", v)
+	}
+	return b, nil
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// equal compares strings with numbers as numbers when the string is one.
+func equal(l, r interface{}) bool {
+	if l == r {
+		return true
+	}
+
+	_, lnum := l.(float64)
+	_, rnum := r.(float64)
+	if lnum != rnum {
+		ln, lerr := toNumber(l)
+		rn, rerr := toNumber(r)
+		return lerr == nil && rerr == nil && ln == rn
+	}
+	return false
+}
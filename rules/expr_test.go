@@ -0,0 +1,95 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	env := &Env{
+		Vars: map[string]interface{}{
+			"old_price":       10000.0,
+			"new_price":       11500.0,
+			"new_stock_level": 1.0,
+			"low_stock":       1.0,
+			"sku":             "SKU-1",
+		},
+		Columns: map[string]string{
+			"Category": "Shoes",
+			"Cost":     "Rp9,000",
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{expr: "1 + 2 * 3", want: 7.0},
+		{expr: "(1 + 2) * 3", want: 9.0},
+		{expr: "-2 - -3", want: 1.0},
+		{expr: "7 % 4", want: 3.0},
+		{expr: `"a" + "b"`, want: "ab"},
+		{expr: "new_price > old_price * 1.1 && new_stock_level == low_stock", want: true},
+		{expr: "new_price > old_price * 1.2 or sku == \"SKU-1\"", want: true},
+		{expr: "not (sku == \"SKU-1\")", want: false},
+		{expr: "!true || false", want: false},
+		{expr: `col("Category") == "Shoes"`, want: true},
+		{expr: `col("Missing") == ""`, want: true},
+		{expr: `(new_price - num(col("Cost"))) / new_price < 0.15`, want: false},
+		{expr: `col("Cost") < 10000`, want: true},
+		{expr: `contains(lower(col("Category")), "sho")`, want: true},
+		{expr: "abs(old_price - new_price)", want: 1500.0},
+		{expr: `"10" == 10`, want: true},
+		{expr: "false && unknown", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := e.Eval(env)
+			if err != nil {
+				t.Fatalf("Expr.Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expr.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		`"open`,
+		"a # b",
+		"nope(1)",
+		"col()",
+		"num(1, 2)",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) error = nil", expr)
+		}
+	}
+}
+
+func TestExpr_Eval_errors(t *testing.T) {
+	env := &Env{Vars: map[string]interface{}{"sku": "SKU-1"}}
+	for _, expr := range []string{
+		"unknown > 1",
+		"sku > 1",
+		"1 / 0",
+		"1 && true",
+	} {
+		e, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", expr, err)
+		}
+		if _, err := e.Eval(env); err == nil {
+			t.Errorf("Eval(%q) error = nil", expr)
+		}
+	}
+}
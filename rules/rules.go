@@ -0,0 +1,251 @@
+// Package rules evaluates custom alert conditions against every checked row,
+// and turns the matches into findings with their own severity and routing.
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/notify"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+const rulesPathEnvKey = "RULES_PATH"
+
+// Rule adds a rule_matched finding for every row its condition holds for.
+// With Kinds it adds nothing, and gives its severity and routes to the row's
+// findings of those kinds instead, like the built-in price_changed ones.
+// Findings with routes only go to those notifiers.
+//
+// The condition sees these names:
+//
+//	old_price, new_price              the CSV and the partner price
+//	old_stock_level, new_stock_level  out_of_stock, low_stock or high_stock
+//	stock                             the partner's stock count
+//	price_change_pct                  how much the price moved, in percent
+//	sku, slug, variant, partner, row
+//
+// the CSV columns of the row through col("Column Name"), and the kinds of
+// the row's findings, baseline changes included, through has("kind").
+type Rule struct {
+	Name     string   `json:"name"`
+	When     string   `json:"when"`
+	Kinds    []string `json:"kinds,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Routes   []string `json:"routes,omitempty"`
+	Message  string   `json:"message,omitempty"`
+
+	expr Expr
+}
+
+type config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Engine holds parsed rules.
+type Engine struct {
+	rules []Rule
+}
+
+// New parses the conditions of rules.
+func New(rules []Rule) (*Engine, error) {
+	e := &Engine{}
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, errors.New("rule without a name")
+		}
+		if r.Severity != "" {
+			if _, err := notify.ParseSeverity(r.Severity); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		}
+
+		expr, err := Parse(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.expr = expr
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Load reads the rules from a JSON file like
+//
+//	{"rules": [
+//		{"name": "price up", "when": "price_change_pct > 10", "severity": "warning", "routes": ["slack"]},
+//		{"name": "stock outs", "when": "true", "kinds": ["stock_level_changed"], "routes": ["email"]}
+//	]}
+func Load(path string) (*Engine, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return New(c.Rules)
+}
+
+// NewEngine loads the rules of RULES_PATH. It returns nil when there is
+// none.
+func NewEngine() (*Engine, error) {
+	path := os.Getenv(rulesPathEnvKey)
+	if path == "" {
+		return nil, nil
+	}
+	return Load(path)
+}
+
+// Apply evaluates every rule against every row the partner answered for,
+// adds the matches to the findings of report and marks the findings rules
+// with kinds match. Rows quarantined as suspicious are skipped. Rules that
+// fail on a row, like num() of a column that isn't a number, are skipped for
+// that row and reported in the error.
+func (e *Engine) Apply(report *checker.Report) error {
+	findings := report.Findings
+	quarantined := map[int]bool{}
+	for _, f := range findings {
+		if f.Kind == checker.KindNeedsReview {
+			quarantined[f.Row] = true
+		}
+	}
+
+	var msgs []string
+	for _, o := range report.Observations {
+		if quarantined[o.Row] {
+			continue
+		}
+
+		env := NewEnv(report.Partner, o)
+		var ofRow []int
+		for i, f := range findings {
+			if isOfRow(f, o) {
+				ofRow = append(ofRow, i)
+				env.Kinds[string(f.Kind)] = true
+			}
+		}
+
+		for _, r := range e.rules {
+			v, err := r.expr.Eval(env)
+			if err == nil {
+				_, ok := v.(bool)
+				if !ok {
+					err = fmt.Errorf("not a bool: %v", v)
+				}
+			}
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("rule %s, row %d: %v", r.Name, o.Row, err))
+				continue
+			}
+			if !v.(bool) {
+				continue
+			}
+
+			if len(r.Kinds) > 0 {
+				for _, i := range ofRow {
+					if contains(r.Kinds, string(report.Findings[i].Kind)) {
+						mark(&report.Findings[i], r)
+					}
+				}
+				continue
+			}
+
+			message := r.Message
+			if message == "" {
+				message = r.Name
+			}
+			report.Findings = append(report.Findings, checker.Finding{
+				Kind:     checker.KindRuleMatched,
+				Partner:  report.Partner,
+				Row:      o.Row,
+				SKU:      o.SKU,
+				Slug:     o.Slug,
+				Variant:  o.Variant.Name,
+				OldValue: o.OldPrice,
+				NewValue: o.Variant.Price,
+				Message:  message,
+				Rule:     r.Name,
+				Severity: r.Severity,
+				Routes:   r.Routes,
+			})
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// isOfRow tells whether f is about the row of o. Baseline changes have no
+// row and go by slug and variant.
+func isOfRow(f checker.Finding, o checker.Observation) bool {
+	if f.Row != 0 {
+		return f.Row == o.Row
+	}
+	return f.Slug == o.Slug && (f.Variant == "" || f.Variant == o.Variant.Name)
+}
+
+// mark gives f the severity and routes of r. The highest severity of the
+// rules marking f wins, and their routes add up.
+func mark(f *checker.Finding, r Rule) {
+	if r.Severity != "" && (f.Severity == "" || severity(r.Severity) > severity(f.Severity)) {
+		f.Severity = r.Severity
+	}
+	for _, route := range r.Routes {
+		if !contains(f.Routes, route) {
+			f.Routes = append(f.Routes, route)
+		}
+	}
+}
+
+func severity(s string) notify.Severity {
+	v, _ := notify.ParseSeverity(s)
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEnv returns what a rule sees of an observed row.
+func NewEnv(partner string, o checker.Observation) *Env {
+	var pct float64
+	if o.OldPrice != 0 {
+		pct = float64(o.Variant.Price-o.OldPrice) / float64(o.OldPrice) * 100
+	}
+
+	return &Env{
+		Vars: map[string]interface{}{
+			"old_price":        float64(o.OldPrice),
+			"new_price":        float64(o.Variant.Price),
+			"old_stock_level":  float64(o.OldStockLevel),
+			"new_stock_level":  float64(o.Variant.StockLevel()),
+			"stock":            float64(o.Variant.Stock),
+			"price_change_pct": pct,
+			"sku":              o.SKU,
+			"slug":             o.Slug,
+			"variant":          o.Variant.Name,
+			"partner":          partner,
+			"row":              float64(o.Row),
+			"out_of_stock":     float64(product.OutOfStock),
+			"low_stock":        float64(product.LowStock),
+			"high_stock":       float64(product.HighStock),
+		},
+		Columns: o.Data,
+		Kinds:   map[string]bool{},
+	}
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestEngine_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	ioutil.WriteFile(path, []byte(`{"rules": [
+		{"name": "price up", "when": "price_change_pct > 10 && new_stock_level == low_stock", "severity": "warning", "routes": ["slack"]},
+		{"name": "low margin", "when": "(new_price - num(col(\"Cost\"))) / new_price < 0.15", "message": "margin under 15%"}
+	]}`), 0644)
+
+	e, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	report := &checker.Report{
+		Partner: "acme",
+		Observations: []checker.Observation{
+			{
+				Row: 1, SKU: "SKU-1", Slug: "red-shirt",
+				Variant:  product.Variant{Name: "L", Price: 11500, Stock: 5},
+				OldPrice: 10000, OldStockLevel: product.HighStock,
+				Data: map[string]string{"Cost": "9000"},
+			},
+			{
+				Row: 2, SKU: "SKU-2", Slug: "old-hat",
+				Variant:  product.Variant{Name: "default", Price: 10000, Stock: 50},
+				OldPrice: 10000, OldStockLevel: product.HighStock,
+				Data: map[string]string{"Cost": "9000"},
+			},
+			{
+				Row: 3, SKU: "SKU-3", Slug: "cap",
+				Variant:  product.Variant{Name: "default", Price: 10000, Stock: 50},
+				OldPrice: 10000, OldStockLevel: product.HighStock,
+				Data: map[string]string{"Cost": "unknown"},
+			},
+		},
+	}
+
+	if err := e.Apply(report); err == nil {
+		t.Errorf("Engine.Apply() error = nil, want the num() error of row 3")
+	}
+
+	want := []checker.Finding{
+		{Kind: checker.KindRuleMatched, Partner: "acme", Row: 1, SKU: "SKU-1", Slug: "red-shirt", Variant: "L", OldValue: 10000, NewValue: 11500, Message: "price up", Rule: "price up", Severity: "warning", Routes: []string{"slack"}},
+		{Kind: checker.KindRuleMatched, Partner: "acme", Row: 2, SKU: "SKU-2", Slug: "old-hat", Variant: "default", OldValue: 10000, NewValue: 10000, Message: "margin under 15%", Rule: "low margin"},
+	}
+	if !reflect.DeepEqual(report.Findings, want) {
+		t.Errorf("Report.Findings = %+v, want %+v", report.Findings, want)
+	}
+}
+
+func TestEngine_Apply_kinds(t *testing.T) {
+	e, err := New([]Rule{
+		{Name: "stock outs", When: "new_stock_level == out_of_stock", Kinds: []string{"stock_level_changed"}, Severity: "critical", Routes: []string{"email"}},
+		{Name: "changed product", When: "has(\"product_changed\")", Severity: "warning"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	report := &checker.Report{
+		Findings: []checker.Finding{
+			{Kind: checker.KindStockLevelChanged, Row: 1, SKU: "SKU-1", OldValue: product.LowStock, NewValue: product.OutOfStock},
+			{Kind: checker.KindProductChanged, Slug: "red-shirt", Variant: "L"},
+			{Kind: checker.KindNeedsReview, Row: 2, SKU: "SKU-2"},
+		},
+		Observations: []checker.Observation{
+			{Row: 1, SKU: "SKU-1", Slug: "red-shirt", Variant: product.Variant{Name: "L", Price: 1000}},
+			{Row: 2, SKU: "SKU-2", Slug: "red-shirt", Variant: product.Variant{Name: "L", Price: 990000}},
+		},
+	}
+	if err := e.Apply(report); err != nil {
+		t.Fatalf("Engine.Apply() error = %v", err)
+	}
+
+	if f := report.Findings[0]; f.Severity != "critical" || !reflect.DeepEqual(f.Routes, []string{"email"}) {
+		t.Errorf("stock level finding severity, routes = %v, %v, want critical, [email]", f.Severity, f.Routes)
+	}
+	if len(report.Findings) != 4 || report.Findings[3].Rule != "changed product" || report.Findings[3].Row != 1 {
+		t.Errorf("Report.Findings = %+v, want one changed product match on row 1", report.Findings)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "no name", rule: Rule{When: "true"}},
+		{name: "bad condition", rule: Rule{Name: "sample", When: "1 +"}},
+		{name: "bad severity", rule: Rule{Name: "sample", When: "true", Severity: "sample"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]Rule{tt.rule}); err == nil {
+				t.Errorf("New() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/andrysds/dropship-checker/api"
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/csv"
+	"github.com/andrysds/dropship-checker/metrics"
+	"github.com/andrysds/dropship-checker/partner"
+)
+
+const (
+	apiTokenEnvKey = "API_TOKEN"
+	apiAddrEnvKey  = "API_ADDR"
+	defaultAPIAddr = ":8080"
+)
+
+func runServe(ctx context.Context, args []string) {
+	addr := os.Getenv(apiAddrEnvKey)
+	if addr == "" {
+		addr = defaultAPIAddr
+	}
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&addr, "addr", addr, "serve the API on this address")
+	reportPath := fs.String("report", "", "write the JSON report of every run to this path")
+	baselinePath := fs.String("baseline", "", "report product changes since this snapshot file, then update it")
+	fs.Parse(args)
+
+	token := os.Getenv(apiTokenEnvKey)
+	if token == "" {
+		log.Fatalln("[ERROR] [serve]", apiTokenEnvKey+" is required")
+	}
+
+	live := newPartner(ctx)
+	defer closePartner(live)
+	p := partner.NewSession(metrics.InstrumentPartner(live, os.Getenv(checker.PartnerNameEnvKey)))
+	metrics.ObserveCache(p)
+
+	// The dashboard reads the history runs write to, through the same store.
+	store := optionalHistoryStore()
+	opts := checkOptions{reportPath: *reportPath, baselinePath: *baselinePath, history: store}
+	records := func() ([]csv.Record, error) {
+		r, _, err := readRecords()
+		return r, err
+	}
+	runAPICheck := func(ctx context.Context, c *checker.Checker) (*checker.Report, error) {
+		ctx, cancel := withRunTimeout(ctx)
+		defer cancel()
+		report, err := check(ctx, c, opts)
+		if err != nil {
+			log.Println("[ERROR] [Check]", err)
+		}
+		return report, err
+	}
+
+	s := api.NewServer(token, p, records, runAPICheck, store)
+	srv := &http.Server{Addr: addr, Handler: s}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln("[ERROR] [serving api]", err)
+		}
+	}()
+	log.Printf("[INFO] serving api; addr: %s\n", addr)
+
+	<-ctx.Done()
+	log.Println("[INFO] stopping after the run in flight")
+	s.Close()
+	if err := srv.Shutdown(context.Background()); err != nil {
+		log.Println("[ERROR] [stopping api]", err)
+	}
+	s.Wait()
+	log.Println("[INFO] api stopped")
+}
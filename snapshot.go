@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/snapshot"
+)
+
+func runSnapshot(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := fs.String("o", "snapshot.json", "snapshot file to write")
+	fs.Parse(args)
+
+	r := loadRecords(ctx)
+
+	p := newPartner(ctx)
+	c := checker.NewChecker(r, p)
+
+	s, err := snapshot.Fetch(ctx, p, c.Slugs())
+	closePartner(p)
+	if s == nil {
+		log.Fatalln("[ERROR] [Fetch]", err)
+	}
+	log.Printf("products saved: %d; slugs: %d\n", len(s.Products), len(c.Slugs()))
+
+	// A partial snapshot is saved too; the slugs it didn't get to are in its
+	// errors, so offline checks don't take them for discontinued products.
+	if err := s.Save(*out); err != nil {
+		log.Fatalln("[ERROR] [saving snapshot]", err)
+	}
+
+	if err != nil {
+		log.Fatalln("[ERROR] [Fetch]", err)
+	}
+}
@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+type ChangeKind string
+
+const (
+	FieldChanged   ChangeKind = "field_changed"
+	VariantAdded   ChangeKind = "variant_added"
+	VariantRemoved ChangeKind = "variant_removed"
+)
+
+// Change is a difference between two versions of a product. Variant is set
+// for changes of a single variant.
+type Change struct {
+	Slug    string     `json:"slug"`
+	Kind    ChangeKind `json:"kind"`
+	Variant string     `json:"variant,omitempty"`
+	Field   string     `json:"field,omitempty"`
+	Old     string     `json:"old,omitempty"`
+	New     string     `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case VariantAdded:
+		return fmt.Sprintf("variant %q added", c.Variant)
+	case VariantRemoved:
+		return fmt.Sprintf("variant %q removed", c.Variant)
+	}
+
+	return fmt.Sprintf("%s changed from %q to %q", c.Field, c.Old, c.New)
+}
+
+// Diff returns the changes from old to new product: its name, description
+// and variants. Variant prices and stock are left to the price and stock
+// level findings of the checker.
+func Diff(slug string, old, new *product.Product) []Change {
+	var res []Change
+
+	field := func(name, o, n string) {
+		if o != n {
+			res = append(res, Change{Slug: slug, Kind: FieldChanged, Field: name, Old: o, New: n})
+		}
+	}
+
+	field("name", old.Name, new.Name)
+	field("description", old.Description, new.Description)
+
+	oldVariants := old.VariantMap()
+	newVariants := new.VariantMap()
+
+	for _, v := range new.Variants {
+		if _, ok := oldVariants[v.Name]; !ok {
+			res = append(res, Change{Slug: slug, Kind: VariantAdded, Variant: v.Name})
+		}
+	}
+
+	for _, v := range old.Variants {
+		if _, ok := newVariants[v.Name]; !ok {
+			res = append(res, Change{Slug: slug, Kind: VariantRemoved, Variant: v.Name})
+		}
+	}
+
+	return res
+}
+
+// Diff compares the products in s with current. Products missing from
+// either side are skipped, since a run may not fetch every slug.
+func (s *Snapshot) Diff(current map[string]*product.Product) []Change {
+	slugs := make([]string, 0, len(current))
+	for slug := range current {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	var res []Change
+	for _, slug := range slugs {
+		if old, ok := s.Products[slug]; ok {
+			res = append(res, Diff(slug, old, current[slug])...)
+		}
+	}
+	return res
+}
+
+// Without returns current without the variants of skip, which maps slugs to
+// variant names. A skipped variant keeps its version in s, or is left out
+// when s doesn't have it, so it neither shows up in Diff nor ends up in s.
+func (s *Snapshot) Without(current map[string]*product.Product, skip map[string]map[string]bool) map[string]*product.Product {
+	res := make(map[string]*product.Product, len(current))
+	for slug, p := range current {
+		if len(skip[slug]) == 0 {
+			res[slug] = p
+			continue
+		}
+
+		var stored map[string]product.Variant
+		if old, ok := s.Products[slug]; ok {
+			stored = old.VariantMap()
+		}
+
+		kept := p.Copy()
+		kept.Variants = kept.Variants[:0]
+		for _, v := range p.Variants {
+			if !skip[slug][v.Name] {
+				kept.Variants = append(kept.Variants, v)
+			} else if old, ok := stored[v.Name]; ok {
+				kept.Variants = append(kept.Variants, old)
+			}
+		}
+		res[slug] = kept
+	}
+	return res
+}
+
+// Update stores the current products in s, keeping the ones not fetched.
+func (s *Snapshot) Update(current map[string]*product.Product) {
+	for slug, p := range current {
+		s.Products[slug] = p
+	}
+}
+
+// Findings turns changes into findings of partner for the check report.
+func Findings(partner string, changes []Change) []checker.Finding {
+	kinds := map[ChangeKind]checker.Kind{
+		FieldChanged:   checker.KindProductChanged,
+		VariantAdded:   checker.KindVariantAdded,
+		VariantRemoved: checker.KindVariantRemoved,
+	}
+
+	res := make([]checker.Finding, 0, len(changes))
+	for _, c := range changes {
+		res = append(res, checker.Finding{
+			Kind:    kinds[c.Kind],
+			Partner: partner,
+			Slug:    c.Slug,
+			Variant: c.Variant,
+			Message: c.String(),
+		})
+	}
+	return res
+}
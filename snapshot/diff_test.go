@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestDiff(t *testing.T) {
+	old := &product.Product{
+		Name:        "sample name",
+		Description: "sample description",
+		Variants: []product.Variant{
+			{Name: "red", Price: 1000, Stock: 10},
+			{Name: "blue", Price: 1000, Stock: 10},
+		},
+	}
+
+	tests := []struct {
+		name string
+		new  *product.Product
+		want []Change
+	}{
+		{
+			name: "nothing changed",
+			new:  old,
+			want: nil,
+		},
+		{
+			name: "price and stock changed",
+			new: &product.Product{
+				Name:        old.Name,
+				Description: old.Description,
+				Variants: []product.Variant{
+					{Name: "red", Price: 1200, Stock: 9},
+					{Name: "blue", Price: 1000, Stock: 3},
+				},
+			},
+			want: nil,
+		},
+		{
+			name: "name and description changed",
+			new: &product.Product{
+				Name:        "new name",
+				Description: "new description",
+				Variants:    old.Variants,
+			},
+			want: []Change{
+				{Slug: "sample-slug", Kind: FieldChanged, Field: "name", Old: "sample name", New: "new name"},
+				{Slug: "sample-slug", Kind: FieldChanged, Field: "description", Old: "sample description", New: "new description"},
+			},
+		},
+		{
+			name: "variants added and removed",
+			new: &product.Product{
+				Name:        old.Name,
+				Description: old.Description,
+				Variants: []product.Variant{
+					{Name: "red", Price: 1000, Stock: 10},
+					{Name: "green", Price: 1000, Stock: 10},
+				},
+			},
+			want: []Change{
+				{Slug: "sample-slug", Kind: VariantAdded, Variant: "green"},
+				{Slug: "sample-slug", Kind: VariantRemoved, Variant: "blue"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff("sample-slug", old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshot_Without(t *testing.T) {
+	s := New()
+	s.Products["sample-slug"] = &product.Product{
+		Name:     "sample name",
+		Variants: []product.Variant{{Name: "red", Price: 12000}, {Name: "blue", Price: 12000}},
+	}
+	current := map[string]*product.Product{
+		"sample-slug": {
+			Name:     "sample name",
+			Variants: []product.Variant{{Name: "red", Price: 0}, {Name: "blue", Price: 13000}, {Name: "green", Price: 1}},
+		},
+		"other-slug": {Name: "other name"},
+	}
+	skip := map[string]map[string]bool{"sample-slug": {"red": true, "green": true}}
+
+	got := s.Without(current, skip)
+	want := []product.Variant{{Name: "red", Price: 12000}, {Name: "blue", Price: 13000}}
+	if !reflect.DeepEqual(got["sample-slug"].Variants, want) {
+		t.Errorf("Snapshot.Without() variants = %v, want %v", got["sample-slug"].Variants, want)
+	}
+	if got["other-slug"] != current["other-slug"] {
+		t.Errorf("Snapshot.Without() changed a product without skipped variants")
+	}
+	if current["sample-slug"].Variants[0].Price != 0 {
+		t.Errorf("Snapshot.Without() changed current")
+	}
+	if changes := s.Diff(got); len(changes) != 0 {
+		t.Errorf("Snapshot.Diff() of the kept products = %v, want none", changes)
+	}
+}
+
+func TestFindings(t *testing.T) {
+	changes := []Change{
+		{Slug: "sample-slug", Kind: FieldChanged, Field: "name", Old: "sample name", New: "new name"},
+		{Slug: "sample-slug", Kind: VariantRemoved, Variant: "blue"},
+	}
+
+	want := []checker.Finding{
+		{
+			Kind:    checker.KindProductChanged,
+			Partner: "acme",
+			Slug:    "sample-slug",
+			Message: `name changed from "sample name" to "new name"`,
+		},
+		{
+			Kind:    checker.KindVariantRemoved,
+			Partner: "acme",
+			Slug:    "sample-slug",
+			Variant: "blue",
+			Message: `variant "blue" removed`,
+		},
+	}
+
+	if got := Findings("acme", changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Findings() = %v, want %v", got, want)
+	}
+}
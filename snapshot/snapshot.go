@@ -0,0 +1,187 @@
+// Package snapshot saves the partner's products to a file, so checks can
+// run against them later without hitting the partner.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+// Version is the snapshot file format version this package writes.
+const Version = 1
+
+// Snapshot holds the products of the slugs it was fetched for. Errors keeps
+// the slugs the partner failed on, other than missing products.
+type Snapshot struct {
+	Version   int                         `json:"version"`
+	CreatedAt time.Time                   `json:"created_at"`
+	Products  map[string]*product.Product `json:"products"`
+	Errors    map[string]string           `json:"errors,omitempty"`
+}
+
+// FetchError is returned for a slug the partner failed on when the snapshot
+// was fetched, so a replayed check doesn't take it for a discontinued
+// product.
+type FetchError struct {
+	Slug    string
+	Message string
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("fetching %s for the snapshot failed: %s", e.Slug, e.Message)
+}
+
+func New() *Snapshot {
+	return &Snapshot{
+		Version:   Version,
+		CreatedAt: time.Now(),
+		Products:  map[string]*product.Product{},
+	}
+}
+
+func Load(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	if s.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", s.Version)
+	}
+
+	if s.Products == nil {
+		s.Products = map[string]*product.Product{}
+	}
+
+	return &s, nil
+}
+
+func (s *Snapshot) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Fetch logs in and gets every slug from p. Slugs that fail are logged and
+// kept in Errors; products the partner doesn't have are left out. When the
+// run is stopped early, the partial snapshot is returned together with the
+// reason, and the slugs it didn't get to are kept in Errors too. A failed
+// login returns no snapshot.
+func Fetch(ctx context.Context, p checker.ContextPartner, slugs []string) (*Snapshot, error) {
+	if err := p.LoginContext(ctx); err != nil {
+		return nil, err
+	}
+
+	s := New()
+	for i, slug := range slugs {
+		if err := ctx.Err(); err != nil {
+			s.notFetched(slugs[i:], err)
+			return s, err
+		}
+
+		prod, err := p.GetProductContext(ctx, slug)
+		if errors.Is(err, partner.ErrBudgetExhausted) || (err != nil && ctx.Err() != nil) {
+			s.notFetched(slugs[i:], err)
+			return s, err
+		}
+		if errors.Is(err, partner.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[ERROR] [GetProduct] slug: %s; %v\n", slug, err)
+			s.addError(slug, err.Error())
+			continue
+		}
+
+		s.Products[slug] = prod
+	}
+
+	return s, nil
+}
+
+// notFetched keeps the slugs a stopped fetch didn't get to in Errors.
+func (s *Snapshot) notFetched(slugs []string, reason error) {
+	for _, slug := range slugs {
+		s.addError(slug, "not fetched: "+reason.Error())
+	}
+}
+
+func (s *Snapshot) addError(slug, msg string) {
+	if s.Errors == nil {
+		s.Errors = map[string]string{}
+	}
+	s.Errors[slug] = msg
+}
+
+// Partner serves products from a snapshot, so Check can run offline. Like
+// the HTTP partner, it has to be logged in before products can be fetched.
+type Partner struct {
+	snapshot *Snapshot
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+func NewPartner(s *Snapshot) *Partner {
+	return &Partner{snapshot: s}
+}
+
+func (p *Partner) Login() error {
+	return p.LoginContext(context.Background())
+}
+
+func (p *Partner) LoginContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loggedIn = true
+	return nil
+}
+
+func (p *Partner) GetProduct(slug string) (*product.Product, error) {
+	return p.GetProductContext(context.Background(), slug)
+}
+
+func (p *Partner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	loggedIn := p.loggedIn
+	p.mu.Unlock()
+
+	if !loggedIn {
+		return nil, partner.ErrUnauthorized
+	}
+
+	if msg, ok := p.snapshot.Errors[slug]; ok {
+		return nil, &FetchError{Slug: slug, Message: msg}
+	}
+
+	prod, ok := p.snapshot.Products[slug]
+	if !ok {
+		return nil, partner.ErrProductNotFound
+	}
+	return prod.Copy(), nil
+}
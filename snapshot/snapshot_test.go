@@ -0,0 +1,126 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andrysds/dropship-checker/checker"
+	"github.com/andrysds/dropship-checker/partner"
+	"github.com/andrysds/dropship-checker/partnertest"
+	"github.com/andrysds/dropship-checker/product"
+)
+
+func TestFetch(t *testing.T) {
+	p := partnertest.NewPartner(partnertest.Products)
+
+	s, err := Fetch(context.Background(), p, []string{"sample-slug", "missing-slug"})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := map[string]*product.Product{"sample-slug": partnertest.Products["sample-slug"]}
+	if !reflect.DeepEqual(s.Products, want) {
+		t.Errorf("Fetch() products = %v, want %v", s.Products, want)
+	}
+}
+
+// brokenPartner fails on broken-slug like a partner answering 500, and runs
+// out of budget on budget-slug.
+type brokenPartner struct {
+	*partnertest.Partner
+}
+
+func (p brokenPartner) GetProductContext(ctx context.Context, slug string) (*product.Product, error) {
+	switch slug {
+	case "broken-slug":
+		return nil, &partner.HTTPError{StatusCode: 500}
+	case "budget-slug":
+		return nil, partner.ErrBudgetExhausted
+	}
+	return p.Partner.GetProductContext(ctx, slug)
+}
+
+func TestFetch_errors(t *testing.T) {
+	p := brokenPartner{partnertest.NewPartner(partnertest.Products)}
+
+	s, err := Fetch(context.Background(), p, []string{"sample-slug", "broken-slug", "missing-slug"})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if _, ok := s.Errors["broken-slug"]; !ok || len(s.Errors) != 1 {
+		t.Errorf("Fetch() errors = %v, want broken-slug only", s.Errors)
+	}
+
+	replay := NewPartner(s)
+	replay.Login()
+	var fetchErr *FetchError
+	if _, err := replay.GetProduct("broken-slug"); !errors.As(err, &fetchErr) {
+		t.Errorf("Partner.GetProduct() of a failed slug error = %v, want a FetchError", err)
+	}
+	if _, err := replay.GetProduct("missing-slug"); !errors.Is(err, partner.ErrProductNotFound) {
+		t.Errorf("Partner.GetProduct() of a missing slug error = %v, want %v", err, partner.ErrProductNotFound)
+	}
+}
+
+func TestFetch_stopped(t *testing.T) {
+	p := brokenPartner{partnertest.NewPartner(partnertest.Products)}
+
+	s, err := Fetch(context.Background(), p, []string{"sample-slug", "budget-slug", "missing-slug"})
+	if !errors.Is(err, partner.ErrBudgetExhausted) {
+		t.Fatalf("Fetch() error = %v, want %v", err, partner.ErrBudgetExhausted)
+	}
+	if len(s.Products) != 1 {
+		t.Errorf("Fetch() products = %v, want sample-slug only", s.Products)
+	}
+
+	replay := NewPartner(s)
+	replay.Login()
+	var fetchErr *FetchError
+	if _, err := replay.GetProduct("missing-slug"); !errors.As(err, &fetchErr) {
+		t.Errorf("Partner.GetProduct() of an unfetched slug error = %v, want a FetchError", err)
+	}
+}
+
+func TestFetch_loginFailed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if s, err := Fetch(ctx, partnertest.NewPartner(partnertest.Products), []string{"sample-slug"}); s != nil || err == nil {
+		t.Errorf("Fetch() = %v, %v, want no snapshot and an error", s, err)
+	}
+}
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	s := New()
+	s.Products = partnertest.Products
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Snapshot.Save() error = %v", err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Products, s.Products) {
+		t.Errorf("Load() products = %v, want %v", got.Products, s.Products)
+	}
+
+	s.Version = Version + 1
+	s.Save(path)
+	if _, err := Load(path); err == nil {
+		t.Errorf("Load() of an unknown version error = nil, want error")
+	}
+}
+
+func TestPartner(t *testing.T) {
+	partnertest.RunConformance(t, func(t *testing.T, products map[string]*product.Product) checker.ContextPartner {
+		s := New()
+		s.Products = products
+		return NewPartner(s)
+	})
+}